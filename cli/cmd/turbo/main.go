@@ -73,6 +73,16 @@ func main() {
 			return &run.RunCommand{Config: cf, UI: ui, SignalWatcher: signalWatcher},
 				nil
 		},
+		"watch": func() (cli.Command, error) {
+			return &run.WatchCommand{Config: cf, UI: ui, SignalWatcher: signalWatcher},
+				nil
+		},
+		"exec": func() (cli.Command, error) {
+			return &run.ExecCommand{Config: cf, UI: ui, SignalWatcher: signalWatcher}, nil
+		},
+		"query": func() (cli.Command, error) {
+			return &run.QueryCommand{Config: cf, UI: ui}, nil
+		},
 		"prune": func() (cli.Command, error) {
 			return &prune.PruneCommand{Config: cf, Ui: ui}, nil
 		},
@@ -148,7 +158,7 @@ func main() {
 			}
 		} else {
 			// Don't disable the GC if this is a long-running process
-			isServe := false
+			isServe := len(args) > 0 && args[0] == "watch"
 			for _, arg := range args {
 				if arg == "--no-gc" {
 					isServe = true
@@ -92,7 +92,11 @@ func NewClient(parent context.Context, sink Sink, logger hclog.Logger) Client {
 }
 
 func (s *client) LogEvent(event EventPayload) {
-	s.ch <- event
+	select {
+	case s.ch <- event:
+	case <-s.worker.ctx.Done():
+		// The worker has stopped, so nothing will receive this event
+	}
 }
 
 func (s *client) Close() {
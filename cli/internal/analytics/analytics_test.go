@@ -158,6 +158,27 @@ func Test_closingByContext(t *testing.T) {
 	}
 }
 
+func Test_logAfterClose(t *testing.T) {
+	d := newDummySink()
+	ctx := context.Background()
+	c := NewClient(ctx, d, hclog.Default())
+	c.Close()
+	done := make(chan struct{})
+	go func() {
+		c.LogEvent(&evt{1})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("expected LogEvent to return after the client was closed")
+	}
+	found := d.Events()
+	if len(found) != 0 {
+		t.Errorf("got %v events, want 0 after the client was closed", len(found))
+	}
+}
+
 func Test_addSessionId(t *testing.T) {
 	events := []struct {
 		Foo string `mapstructure:"foo"`
@@ -24,6 +24,8 @@ type cacheRequest struct {
 	key      string
 	duration int
 	files    []string
+	// done is called once the artifact has been written, if it is set
+	done func()
 }
 
 func newAsyncCache(realCache Cache, opts Opts) Cache {
@@ -48,10 +50,28 @@ func (c *asyncCache) Put(target string, key string, duration int, files []string
 	return nil
 }
 
+func (c *asyncCache) putThen(target string, key string, duration int, files []string, done func()) {
+	c.requests <- cacheRequest{
+		target:   target,
+		key:      key,
+		files:    files,
+		duration: duration,
+		done:     done,
+	}
+}
+
 func (c *asyncCache) Fetch(target string, key string, files []string) (bool, []string, int, error) {
 	return c.realCache.Fetch(target, key, files)
 }
 
+func (c *asyncCache) fetchWithSource(target string, key string, files []string) (bool, []string, int, string, error) {
+	return fetchWithSource(c.realCache, target, key, files)
+}
+
+func (c *asyncCache) exists(hash string) (ArtifactStatus, error) {
+	return Exists(c.realCache, hash)
+}
+
 func (c *asyncCache) Clean(target string) {
 	c.realCache.Clean(target)
 }
@@ -71,6 +91,9 @@ func (c *asyncCache) Shutdown() {
 func (c *asyncCache) run() {
 	for r := range c.requests {
 		c.realCache.Put(r.target, r.key, r.duration, r.files)
+		if r.done != nil {
+			r.done()
+		}
 	}
 	c.wg.Done()
 }
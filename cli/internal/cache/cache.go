@@ -7,14 +7,12 @@ package cache
 
 import (
 	"errors"
-	"fmt"
 	"sync"
 
 	"github.com/spf13/pflag"
 	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/config"
 	"github.com/vercel/turborepo/cli/internal/fs"
-	"github.com/vercel/turborepo/cli/internal/ui"
 	"github.com/vercel/turborepo/cli/internal/util"
 	"golang.org/x/sync/errgroup"
 )
@@ -34,6 +32,72 @@ type Cache interface {
 const cacheEventHit = "HIT"
 const cacheEventMiss = "MISS"
 
+const (
+	// CacheSourceFS is the source of hits from the local filesystem cache
+	CacheSourceFS = "LOCAL"
+	// CacheSourceRemote is the source of hits from the remote cache
+	CacheSourceRemote = "REMOTE"
+)
+
+// sourceFetcher is implemented by caches which know which cache a hit came from
+type sourceFetcher interface {
+	fetchWithSource(target string, hash string, files []string) (bool, []string, int, string, error)
+}
+
+func fetchWithSource(cache Cache, target string, hash string, files []string) (bool, []string, int, string, error) {
+	if sf, ok := cache.(sourceFetcher); ok {
+		return sf.fetchWithSource(target, hash, files)
+	}
+	ok, actualFiles, duration, err := cache.Fetch(target, hash, files)
+	return ok, actualFiles, duration, "", err
+}
+
+// FetchWithSource is like Fetch, but also returns which cache a hit came from:
+// CacheSourceFS, CacheSourceRemote, or "" if it isn't known. The duration is how
+// long the task took to execute when it was cached, in milliseconds.
+func FetchWithSource(cache Cache, target string, hash string, files []string) (bool, int, string, error) {
+	ok, _, duration, source, err := fetchWithSource(cache, target, hash, files)
+	return ok, duration, source, err
+}
+
+// ArtifactStatus says which caches hold the artifact for a hash
+type ArtifactStatus struct {
+	Local  bool `json:"local"`
+	Remote bool `json:"remote"`
+}
+
+// existenceChecker is implemented by caches which can tell whether they hold an
+// artifact without retrieving it
+type existenceChecker interface {
+	exists(hash string) (ArtifactStatus, error)
+}
+
+// Exists reports which caches hold the artifact for hash, without retrieving it.
+// Caches which can't check for an artifact report that they don't hold it.
+func Exists(cache Cache, hash string) (ArtifactStatus, error) {
+	if ec, ok := cache.(existenceChecker); ok {
+		return ec.exists(hash)
+	}
+	return ArtifactStatus{}, nil
+}
+
+// backgroundPutter is implemented by caches which store artifacts in the background
+type backgroundPutter interface {
+	putThen(target string, hash string, duration int, files []string, done func())
+}
+
+// PutThen stores an artifact like Put, and calls done once it has been written.
+// Put returns before the artifact is written when the cache stores artifacts in the
+// background, in which case done is called later, from another goroutine.
+func PutThen(cache Cache, target string, hash string, duration int, files []string, done func()) error {
+	if bp, ok := cache.(backgroundPutter); ok {
+		bp.putThen(target, hash, duration, files, done)
+		return nil
+	}
+	defer done()
+	return cache.Put(target, hash, duration, files)
+}
+
 type CacheEvent struct {
 	Source   string `mapstructure:"source"`
 	Event    string `mapstructure:"event"`
@@ -114,7 +178,6 @@ func newSyncCache(opts Opts, config *config.Config, client client, recorder anal
 	}
 
 	if useHTTPCache {
-		fmt.Println(ui.Dim("• Remote computation caching enabled"))
 		implementation := newHTTPCache(opts, config, client, recorder, config.Cwd)
 		cacheImplementations = append(cacheImplementations, implementation)
 	}
@@ -227,6 +290,11 @@ func (mplex *cacheMultiplexer) removeCache(removal *cacheRemoval) {
 }
 
 func (mplex *cacheMultiplexer) Fetch(target string, key string, files []string) (bool, []string, int, error) {
+	ok, actualFiles, duration, _, err := mplex.fetchWithSource(target, key, files)
+	return ok, actualFiles, duration, err
+}
+
+func (mplex *cacheMultiplexer) fetchWithSource(target string, key string, files []string) (bool, []string, int, string, error) {
 	// Make a shallow copy of the caches, since storeUntil can call removeCache
 	mplex.mu.RLock()
 	caches := make([]Cache, len(mplex.caches))
@@ -236,7 +304,7 @@ func (mplex *cacheMultiplexer) Fetch(target string, key string, files []string)
 	// Retrieve from caches sequentially; if we did them simultaneously we could
 	// easily write the same file from two goroutines at once.
 	for i, cache := range caches {
-		ok, actualFiles, duration, err := cache.Fetch(target, key, files)
+		ok, actualFiles, duration, source, err := fetchWithSource(cache, target, key, files)
 		if err != nil {
 			cd := &util.CacheDisabledError{}
 			if errors.As(err, &cd) {
@@ -255,10 +323,31 @@ func (mplex *cacheMultiplexer) Fetch(target string, key string, files []string)
 			// we have previously successfully stored in a higher-priority cache, and so the overall
 			// result is a success at fetching. Storing in lower-priority caches is an optimization.
 			_ = mplex.storeUntil(target, key, duration, actualFiles, i)
-			return ok, actualFiles, duration, err
+			return ok, actualFiles, duration, source, err
+		}
+	}
+	return false, files, 0, "", nil
+}
+
+// exists checks every cache. If one of them fails, the status of the others is
+// still returned, along with the first error.
+func (mplex *cacheMultiplexer) exists(hash string) (ArtifactStatus, error) {
+	mplex.mu.RLock()
+	caches := make([]Cache, len(mplex.caches))
+	copy(caches, mplex.caches)
+	mplex.mu.RUnlock()
+
+	status := ArtifactStatus{}
+	var firstErr error
+	for _, cache := range caches {
+		cacheStatus, err := Exists(cache, hash)
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
+		status.Local = status.Local || cacheStatus.Local
+		status.Remote = status.Remote || cacheStatus.Remote
 	}
-	return false, files, 0, nil
+	return status, firstErr
 }
 
 func (mplex *cacheMultiplexer) Clean(target string) {
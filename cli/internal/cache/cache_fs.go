@@ -59,6 +59,15 @@ func (f *fsCache) Fetch(target, hash string, _unusedOutputGlobs []string) (bool,
 	return true, nil, meta.Duration, nil
 }
 
+func (f *fsCache) fetchWithSource(target, hash string, files []string) (bool, []string, int, string, error) {
+	ok, actualFiles, duration, err := f.Fetch(target, hash, files)
+	return ok, actualFiles, duration, CacheSourceFS, err
+}
+
+func (f *fsCache) exists(hash string) (ArtifactStatus, error) {
+	return ArtifactStatus{Local: fs.PathExists(filepath.Join(f.cacheDirectory, hash))}, nil
+}
+
 func (f *fsCache) logFetch(hit bool, hash string, duration int) {
 	var event string
 	if hit {
@@ -67,7 +76,7 @@ func (f *fsCache) logFetch(hit bool, hash string, duration int) {
 		event = cacheEventMiss
 	}
 	payload := &CacheEvent{
-		Source:   "LOCAL",
+		Source:   CacheSourceFS,
 		Event:    event,
 		Hash:     hash,
 		Duration: duration,
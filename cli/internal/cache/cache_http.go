@@ -26,6 +26,7 @@ import (
 type client interface {
 	PutArtifact(hash string, body []byte, duration int, tag string) error
 	FetchArtifact(hash string) (*http.Response, error)
+	ArtifactExists(hash string) (bool, error)
 }
 
 type httpCache struct {
@@ -150,6 +151,21 @@ func (cache *httpCache) Fetch(target, key string, _unusedOutputGlobs []string) (
 	return hit, files, duration, err
 }
 
+func (cache *httpCache) fetchWithSource(target, key string, files []string) (bool, []string, int, string, error) {
+	ok, actualFiles, duration, err := cache.Fetch(target, key, files)
+	return ok, actualFiles, duration, CacheSourceRemote, err
+}
+
+func (cache *httpCache) exists(hash string) (ArtifactStatus, error) {
+	cache.requestLimiter.acquire()
+	defer cache.requestLimiter.release()
+	ok, err := cache.client.ArtifactExists(hash)
+	if err != nil {
+		return ArtifactStatus{}, fmt.Errorf("failed to check HTTP cache: %w", err)
+	}
+	return ArtifactStatus{Remote: ok}, nil
+}
+
 func (cache *httpCache) logFetch(hit bool, hash string, duration int) {
 	var event string
 	if hit {
@@ -158,7 +174,7 @@ func (cache *httpCache) logFetch(hit bool, hash string, duration int) {
 		event = cacheEventMiss
 	}
 	payload := &CacheEvent{
-		Source:   "REMOTE",
+		Source:   CacheSourceRemote,
 		Event:    event,
 		Hash:     hash,
 		Duration: duration,
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"errors"
+	"net/http"
 	"os"
 	"reflect"
 	"sync/atomic"
@@ -151,6 +153,89 @@ func TestFetchCachingDisabled(t *testing.T) {
 	mplex.mu.RUnlock()
 }
 
+// existsClient is a remote cache client which only answers existence checks
+type existsClient struct {
+	hashes map[string]bool
+	err    error
+}
+
+func (ec *existsClient) PutArtifact(hash string, body []byte, duration int, tag string) error {
+	return nil
+}
+
+func (ec *existsClient) FetchArtifact(hash string) (*http.Response, error) {
+	return nil, errors.New("unexpected fetch")
+}
+
+func (ec *existsClient) ArtifactExists(hash string) (bool, error) {
+	return ec.hashes[hash], ec.err
+}
+
+func TestExists(t *testing.T) {
+	dir := fs.AbsolutePath(t.TempDir())
+	if err := dir.Join("local-hash").MkdirAll(); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	local := &fsCache{cacheDirectory: dir.ToString()}
+	remote := &httpCache{
+		client:         &existsClient{hashes: map[string]bool{"local-hash": true, "remote-hash": true}},
+		requestLimiter: make(limiter, 1),
+	}
+	mplex := &cacheMultiplexer{caches: []Cache{local, remote}}
+
+	cases := map[string]ArtifactStatus{
+		"local-hash":   {Local: true, Remote: true},
+		"remote-hash":  {Remote: true},
+		"missing-hash": {},
+	}
+	for hash, want := range cases {
+		got, err := Exists(mplex, hash)
+		if err != nil {
+			t.Errorf("Exists(%v) got error %v", hash, err)
+		}
+		if got != want {
+			t.Errorf("Exists(%v) = %+v, want %+v", hash, got, want)
+		}
+	}
+
+	// A failing cache doesn't hide what the others hold
+	remote.client = &existsClient{err: errors.New("offline")}
+	got, err := Exists(mplex, "local-hash")
+	if err == nil {
+		t.Error("Exists got <nil> error, want the remote cache's error")
+	}
+	if got != (ArtifactStatus{Local: true}) {
+		t.Errorf("Exists(local-hash) = %+v, want only a local hit", got)
+	}
+
+	// Caches which can't check for artifacts never report them
+	got, err = Exists(newEnabledCache(), "local-hash")
+	if err != nil || got != (ArtifactStatus{}) {
+		t.Errorf("Exists on testCache = %+v, %v, want no artifacts and no error", got, err)
+	}
+}
+
+func TestPutThen(t *testing.T) {
+	realCache := newEnabledCache()
+	c := newAsyncCache(realCache, Opts{Workers: 1})
+	done := make(chan struct{})
+	if err := PutThen(c, "unused-target", "some-hash", 5, []string{"a-file"}, func() { close(done) }); err != nil {
+		t.Fatalf("PutThen got error %v, want <nil>", err)
+	}
+	<-done
+	// done is only called once the artifact has been written
+	if _, ok := realCache.entries["some-hash"]; !ok {
+		t.Error("artifact was not written before done was called")
+	}
+	c.Shutdown()
+
+	// Caches which store artifacts synchronously call done before returning
+	called := false
+	if err := PutThen(newEnabledCache(), "unused-target", "some-hash", 5, nil, func() { called = true }); err != nil || !called {
+		t.Errorf("PutThen on testCache got %v, called %v, want <nil>, true", err, called)
+	}
+}
+
 type nullRecorder struct{}
 
 func (nullRecorder) LogEvent(analytics.EventPayload) {}
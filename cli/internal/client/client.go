@@ -270,6 +270,39 @@ func (c *ApiClient) PutArtifact(hash string, artifactBody []byte, duration int,
 // FetchArtifact attempts to retrieve the build artifact with the given hash from the
 // Remote Caching server
 func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
+	resp, err := c.requestArtifact(http.MethodGet, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch artifact: %w", err)
+	} else if resp.StatusCode == http.StatusForbidden {
+		err = c.handle403(resp.Body)
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ArtifactExists checks whether the Remote Caching server has the build artifact with
+// the given hash, without downloading it
+func (c *ApiClient) ArtifactExists(hash string) (bool, error) {
+	resp, err := c.requestArtifact(http.MethodHead, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for artifact: %w", err)
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		// HEAD responses have no body, so the reason for a 403 isn't known
+		return false, fmt.Errorf("failed to check for artifact: %v", resp.Status)
+	}
+}
+
+// requestArtifact makes a request with the given method to the endpoint for the
+// build artifact with the given hash
+func (c *ApiClient) requestArtifact(method string, hash string) (*http.Response, error) {
 	if err := c.okToRequest(); err != nil {
 		return nil, err
 	}
@@ -284,7 +317,7 @@ func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
 	requestURL := c.makeUrl("/v8/artifacts/" + hash + encoded)
 	allowAuth := true
 	if c.usePreflight {
-		resp, latestRequestURL, err := c.doPreflight(requestURL, http.MethodGet, "Authorization, User-Agent")
+		resp, latestRequestURL, err := c.doPreflight(requestURL, method, "Authorization, User-Agent")
 		if err != nil {
 			return nil, fmt.Errorf("pre-flight request failed before trying to fetch files in HTTP cache: %w", err)
 		}
@@ -293,24 +326,16 @@ func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
 		allowAuth = strings.Contains(strings.ToLower(headers), strings.ToLower("Authorization"))
 	}
 
-	req, err := retryablehttp.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := retryablehttp.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache URL: %w", err)
+	}
 	if allowAuth {
 		req.Header.Set("Authorization", "Bearer "+c.Token)
 	}
 	req.Header.Set("User-Agent", c.UserAgent())
-	if err != nil {
-		return nil, fmt.Errorf("invalid cache URL: %w", err)
-	}
 
-	resp, err := c.HttpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch artifact: %v", err)
-	} else if resp.StatusCode == http.StatusForbidden {
-		err = c.handle403(resp.Body)
-		_ = resp.Body.Close()
-		return nil, err
-	}
-	return resp, nil
+	return c.HttpClient.Do(req)
 }
 
 func (c *ApiClient) RecordAnalyticsEvents(events []map[string]interface{}) error {
@@ -141,3 +141,35 @@ func Test_FetchWhenCachingDisabled(t *testing.T) {
 		t.Errorf("response got %v, want <nil>", resp)
 	}
 }
+
+func Test_ArtifactExists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() { _ = req.Body.Close() }()
+		if req.Method != http.MethodHead {
+			t.Errorf("method got %v, want %v", req.Method, http.MethodHead)
+		}
+		switch req.URL.Path {
+		case "/v8/artifacts/present":
+			w.WriteHeader(200)
+		case "/v8/artifacts/forbidden":
+			w.WriteHeader(403)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	apiClient := NewClient(ts.URL, hclog.Default(), "v1", "", "my-team-slug", 1, false)
+	apiClient.SetToken("my-token")
+	exists, err := apiClient.ArtifactExists("present")
+	if err != nil || !exists {
+		t.Errorf("ArtifactExists(present) got %v, %v, want true, <nil>", exists, err)
+	}
+	exists, err = apiClient.ArtifactExists("missing")
+	if err != nil || exists {
+		t.Errorf("ArtifactExists(missing) got %v, %v, want false, <nil>", exists, err)
+	}
+	if _, err := apiClient.ArtifactExists("forbidden"); err == nil {
+		t.Error("ArtifactExists(forbidden) got <nil>, want an error")
+	}
+}
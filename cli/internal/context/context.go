@@ -33,13 +33,26 @@ type Context struct {
 	TopologicalGraph dag.AcyclicGraph
 	RootNode         string
 	GlobalHash       string
+	GlobalHashInputs *GlobalHashInputs
 	Lockfile         *fs.YarnLockfile
+	BerryLockfile    *fs.BerryLockfile
 	PackageManager   *packagemanager.PackageManager
 	// Used to arbitrate access to the graph. We parallelise most build operations
 	// and Go maps aren't natively threadsafe so this is needed.
 	mutex sync.Mutex
 }
 
+// GlobalHashInputs are the inputs to the global hash. Environment variables are
+// listed by name only, so that their values aren't exposed.
+type GlobalHashInputs struct {
+	// Files maps each global dependency to the hash of its contents
+	Files map[turbopath.AnchoredUnixPath]string
+	// RootExternalDepsHash is the hash of the root package's external dependencies
+	RootExternalDepsHash string
+	// EnvVars are the names of the environment variables included in the hash
+	EnvVars []string
+}
+
 // Option is used to configure context
 type Option func(*Context) error
 
@@ -137,7 +150,13 @@ func WithGraph(config *config.Config, turboJSON *fs.TurboJSON, cacheDir fs.Absol
 		}
 
 		// this should go into the packagemanager abstraction
-		if util.IsYarn(c.PackageManager.Name) {
+		if c.PackageManager.Name == "nodejs-berry" {
+			lockfile, err := fs.ReadBerryLockfile(config.Cwd.Join(c.PackageManager.Lockfile), config.RootPackageJSON.Resolutions)
+			if err != nil {
+				return fmt.Errorf("yarn.lock: %w", err)
+			}
+			c.BerryLockfile = lockfile
+		} else if util.IsYarn(c.PackageManager.Name) {
 			lockfile, err := fs.ReadLockfile(rootpath, c.PackageManager.Name, cacheDir)
 			if err != nil {
 				return fmt.Errorf("yarn.lock: %w", err)
@@ -152,7 +171,7 @@ func WithGraph(config *config.Config, turboJSON *fs.TurboJSON, cacheDir fs.Absol
 
 		// TODO: it seems like calculating the global hash could be separate from
 		// construction of the package-dependency graph
-		globalHash, err := calculateGlobalHash(
+		globalHash, globalHashInputs, err := calculateGlobalHash(
 			config.Cwd,
 			config.RootPackageJSON,
 			turboJSON.Pipeline,
@@ -166,6 +185,7 @@ func WithGraph(config *config.Config, turboJSON *fs.TurboJSON, cacheDir fs.Absol
 		}
 
 		c.GlobalHash = globalHash
+		c.GlobalHashInputs = globalHashInputs
 
 		// Get the workspaces from the package manager.
 		workspaces, err := c.PackageManager.GetWorkspaces(config.Cwd)
@@ -232,6 +252,7 @@ func (c *Context) resolveWorkspaceRootDeps(rootPackageJSON *fs.PackageJSON) erro
 	}
 	if util.IsYarn(c.PackageManager.Name) {
 		pkg.SubLockfile = make(fs.YarnLockfile)
+		pkg.SubBerryLockfile = make(map[string]*fs.BerryLockfileEntry)
 		c.resolveDepGraph(&lockfileWg, pkg.UnresolvedExternalDeps, depSet, seen, pkg)
 		lockfileWg.Wait()
 		pkg.ExternalDeps = make([]string, 0, depSet.Cardinality())
@@ -303,6 +324,18 @@ func (c *Context) populateTopologicGraphForPackageJSON(pkg *fs.PackageJSON, root
 	}
 
 	pkg.SubLockfile = make(fs.YarnLockfile)
+	pkg.SubBerryLockfile = make(map[string]*fs.BerryLockfileEntry)
+	if c.BerryLockfile != nil {
+		// Berry records references to other workspaces in the lockfile as well, and a pruned
+		// lockfile needs to keep them
+		for _, name := range internalDepsSet.List() {
+			name := name.(string)
+			if descriptor, ok := c.BerryLockfile.ResolveDescriptor(pkg.Name, name, depMap[name]); ok {
+				entry, _ := c.BerryLockfile.Entry(descriptor)
+				pkg.SubBerryLockfile[descriptor] = entry
+			}
+		}
+	}
 	seen := mapset.NewSet()
 	var lockfileWg sync.WaitGroup
 	c.resolveDepGraph(&lockfileWg, pkg.UnresolvedExternalDeps, externalDepSet, seen, pkg)
@@ -355,6 +388,10 @@ func (c *Context) resolveDepGraph(wg *sync.WaitGroup, unresolvedDirectDeps map[s
 	if !util.IsYarn(c.PackageManager.Name) {
 		return
 	}
+	if c.BerryLockfile != nil {
+		c.resolveBerryDepGraph(wg, pkg.Name, unresolvedDirectDeps, resolvedDepsSet, seen, pkg)
+		return
+	}
 	for directDepName, unresolvedVersion := range unresolvedDirectDeps {
 		wg.Add(1)
 		go func(directDepName, unresolvedVersion string) {
@@ -399,6 +436,48 @@ func (c *Context) resolveDepGraph(wg *sync.WaitGroup, unresolvedDirectDeps map[s
 	}
 }
 
+// resolveBerryDepGraph walks the yarn v2+ lockfile starting at the given dependencies, recording
+// every descriptor reached in the package's SubBerryLockfile. parent is the name of the package
+// declaring unresolvedDirectDeps, which is needed to apply resolutions scoped to a parent package.
+func (c *Context) resolveBerryDepGraph(wg *sync.WaitGroup, parent string, unresolvedDirectDeps map[string]string, resolvedDepsSet mapset.Set, seen mapset.Set, pkg *fs.PackageJSON) {
+	for directDepName, unresolvedVersion := range unresolvedDirectDeps {
+		wg.Add(1)
+		go func(directDepName, unresolvedVersion string) {
+			defer wg.Done()
+			descriptor, ok := c.BerryLockfile.ResolveDescriptor(parent, directDepName, unresolvedVersion)
+			if !ok {
+				return
+			}
+			c.addBerryDescriptor(wg, directDepName, descriptor, resolvedDepsSet, seen, pkg)
+		}(directDepName, unresolvedVersion)
+	}
+}
+
+func (c *Context) addBerryDescriptor(wg *sync.WaitGroup, name string, descriptor string, resolvedDepsSet mapset.Set, seen mapset.Set, pkg *fs.PackageJSON) {
+	// Add reports whether the descriptor was newly added, which makes it safe to use
+	// as the only check when walking concurrently
+	if !seen.Add(descriptor) {
+		return
+	}
+	entry, _ := c.BerryLockfile.Entry(descriptor)
+	pkg.Mu.Lock()
+	pkg.SubBerryLockfile[descriptor] = entry
+	pkg.Mu.Unlock()
+	if entry.LinkType == "soft" && strings.Contains(entry.Resolution, "@workspace:") {
+		// Workspaces are part of the topological graph, their dependencies belong to them
+		return
+	}
+	resolvedDepsSet.Add(fmt.Sprintf("%v@%v", name, entry.Version))
+
+	// A patched package needs the entry for the package it patches
+	for _, inner := range c.BerryLockfile.DescriptorDependencies(descriptor) {
+		c.addBerryDescriptor(wg, name, inner, resolvedDepsSet, seen, pkg)
+	}
+	if len(entry.Dependencies) > 0 {
+		c.resolveBerryDepGraph(wg, name, entry.Dependencies, resolvedDepsSet, seen, pkg)
+	}
+}
+
 // getHashableTurboEnvVarsFromOs returns a list of environment variables names and
 // that are safe to include in the global hash
 func getHashableTurboEnvVarsFromOs(env []string) ([]string, []string) {
@@ -419,7 +498,7 @@ var _defaultEnvVars = []string{
 	"VERCEL_ANALYTICS_ID",
 }
 
-func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON, pipeline fs.Pipeline, externalGlobalDependencies []string, packageManager *packagemanager.PackageManager, logger hclog.Logger, env []string) (string, error) {
+func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON, pipeline fs.Pipeline, externalGlobalDependencies []string, packageManager *packagemanager.PackageManager, logger hclog.Logger, env []string) (string, *GlobalHashInputs, error) {
 	// Calculate the global hash
 	globalDeps := make(util.Set)
 
@@ -445,12 +524,12 @@ func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJS
 		if len(globs) > 0 {
 			ignores, err := packageManager.GetWorkspaceIgnores(rootpath)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
 
 			f, err := globby.GlobFiles(rootpath.ToStringDuringMigration(), globs, ignores)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
 
 			for _, val := range f {
@@ -484,7 +563,7 @@ func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJS
 
 	globalFileHashMap, err := fs.GetHashableDeps(rootpath, globalDepsPaths)
 	if err != nil {
-		return "", fmt.Errorf("error hashing files. make sure that git has been initialized %w", err)
+		return "", nil, fmt.Errorf("error hashing files. make sure that git has been initialized %w", err)
 	}
 	globalHashable := struct {
 		globalFileHashMap    map[turbopath.AnchoredUnixPath]string
@@ -501,7 +580,11 @@ func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJS
 	}
 	globalHash, err := fs.HashObject(globalHashable)
 	if err != nil {
-		return "", fmt.Errorf("error hashing global dependencies %w", err)
+		return "", nil, fmt.Errorf("error hashing global dependencies %w", err)
 	}
-	return globalHash, nil
+	return globalHash, &GlobalHashInputs{
+		Files:                globalFileHashMap,
+		RootExternalDepsHash: rootPackageJSON.ExternalDepsHash,
+		EnvVars:              globalHashableEnvNames,
+	}, nil
 }
@@ -3,7 +3,13 @@ package context
 import (
 	"path/filepath"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+
+	mapset "github.com/deckarep/golang-set"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/packagemanager"
 )
 
 func Test_getHashableTurboEnvVarsFromOs(t *testing.T) {
@@ -142,3 +148,88 @@ func Test_isWorkspaceReference(t *testing.T) {
 		})
 	}
 }
+
+const _berryLockfile = `__metadata:
+  version: 6
+  cacheKey: 8
+
+"is-number@npm:^6.0.0":
+  version: 6.0.0
+  resolution: "is-number@npm:6.0.0"
+  languageName: node
+  linkType: hard
+
+"is-odd@npm:3.0.1":
+  version: 3.0.1
+  resolution: "is-odd@npm:3.0.1"
+  dependencies:
+    is-number: ^6.0.0
+  languageName: node
+  linkType: hard
+
+"is-odd@patch:is-odd@npm%3A3.0.1#./is-odd.patch::version=3.0.1&hash=9a1b2c&locator=web%40workspace%3Aapps%2Fweb":
+  version: 3.0.1
+  resolution: "is-odd@patch:is-odd@npm%3A3.0.1#./is-odd.patch::version=3.0.1&hash=9a1b2c&locator=web%40workspace%3Aapps%2Fweb"
+  dependencies:
+    is-number: ^6.0.0
+  languageName: node
+  linkType: hard
+
+"left-pad@npm:^1.3.0":
+  version: 1.3.0
+  resolution: "left-pad@npm:1.3.0"
+  languageName: node
+  linkType: hard
+
+"ui@workspace:*, ui@workspace:packages/ui":
+  version: 0.0.0-use.local
+  resolution: "ui@workspace:packages/ui"
+  dependencies:
+    left-pad: ^1.3.0
+  languageName: unknown
+  linkType: soft
+`
+
+func Test_resolveBerryDepGraph(t *testing.T) {
+	lockfile, err := fs.ParseBerryLockfile([]byte(_berryLockfile), nil)
+	if err != nil {
+		t.Fatalf("failed to parse lockfile: %v", err)
+	}
+	c := &Context{
+		BerryLockfile: lockfile,
+		PackageManager: &packagemanager.PackageManager{
+			Name: "nodejs-berry",
+		},
+	}
+	pkg := &fs.PackageJSON{
+		Name:             "web",
+		SubBerryLockfile: make(map[string]*fs.BerryLockfileEntry),
+	}
+	deps := map[string]string{
+		"is-odd": "patch:is-odd@npm:3.0.1#./is-odd.patch",
+		"ui":     "workspace:*",
+	}
+	resolved := mapset.NewSet()
+	var wg sync.WaitGroup
+	c.resolveDepGraph(&wg, deps, resolved, mapset.NewSet(), pkg)
+	wg.Wait()
+
+	gotDescriptors := []string{}
+	for descriptor := range pkg.SubBerryLockfile {
+		gotDescriptors = append(gotDescriptors, descriptor)
+	}
+	sort.Strings(gotDescriptors)
+	wantDescriptors := []string{
+		"is-number@npm:^6.0.0",
+		"is-odd@npm:3.0.1",
+		"is-odd@patch:is-odd@npm%3A3.0.1#./is-odd.patch::version=3.0.1&hash=9a1b2c&locator=web%40workspace%3Aapps%2Fweb",
+		// workspaces are recorded, but their dependencies belong to them
+		"ui@workspace:*",
+	}
+	if !reflect.DeepEqual(gotDescriptors, wantDescriptors) {
+		t.Errorf("resolveDepGraph() descriptors got = %v, want %v", gotDescriptors, wantDescriptors)
+	}
+	if !resolved.Contains("is-number@6.0.0") || resolved.Contains("left-pad@1.3.0") {
+		t.Errorf("resolveDepGraph() external deps got = %v", resolved)
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import "sync"
+
+// Resources describes the share of the machine used by a task, or the total
+// budget available to all running tasks.
+type Resources struct {
+	// CPU is the number of concurrency slots a task occupies
+	CPU int
+	// MemoryMB is the amount of memory a task uses. A budget of 0 is unlimited.
+	MemoryMB int
+}
+
+// DefaultResources are used by tasks that don't declare their resources, so that
+// each one counts once against the concurrency budget
+var DefaultResources = Resources{CPU: 1}
+
+func (r Resources) add(other Resources) Resources {
+	return Resources{CPU: r.CPU + other.CPU, MemoryMB: r.MemoryMB + other.MemoryMB}
+}
+
+func (r Resources) sub(other Resources) Resources {
+	return Resources{CPU: r.CPU - other.CPU, MemoryMB: r.MemoryMB - other.MemoryMB}
+}
+
+// resourcePool admits tasks against a total budget of resources. Waiting tasks are
+// admitted strictly in order of priority, and then in the order they asked, so that
+// a large task is not starved by a stream of smaller ones.
+type resourcePool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   Resources
+	used    Resources
+	waiters []*poolWaiter
+	// nextTicket is handed to the next caller of acquire, to order waiters of equal priority
+	nextTicket uint64
+}
+
+type poolWaiter struct {
+	priority int64
+	ticket   uint64
+}
+
+func (w *poolWaiter) before(other *poolWaiter) bool {
+	if w.priority != other.priority {
+		return w.priority > other.priority
+	}
+	return w.ticket < other.ticket
+}
+
+func newResourcePool(limit Resources) *resourcePool {
+	if limit.CPU <= 0 {
+		panic("resource pool with cpu limit <=0")
+	}
+	rp := &resourcePool{limit: limit}
+	rp.cond = sync.NewCond(&rp.mu)
+	return rp
+}
+
+// clamp reduces a request to at most the total budget, so that a task which asks for
+// more than is available runs on its own, rather than never running at all.
+func (rp *resourcePool) clamp(r Resources) Resources {
+	if r.CPU > rp.limit.CPU {
+		r.CPU = rp.limit.CPU
+	}
+	if rp.limit.MemoryMB == 0 {
+		r.MemoryMB = 0
+	} else if r.MemoryMB > rp.limit.MemoryMB {
+		r.MemoryMB = rp.limit.MemoryMB
+	}
+	return r
+}
+
+func (rp *resourcePool) fits(r Resources) bool {
+	free := rp.limit.sub(rp.used)
+	return r.CPU <= free.CPU && r.MemoryMB <= free.MemoryMB
+}
+
+// acquire blocks until the requested resources are available and no waiter with a
+// higher priority is ahead, and returns the resources that were actually acquired,
+// which must be passed to release.
+func (rp *resourcePool) acquire(r Resources, priority int64) Resources {
+	r = rp.clamp(r)
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	waiter := &poolWaiter{priority: priority, ticket: rp.nextTicket}
+	rp.nextTicket++
+	rp.waiters = append(rp.waiters, waiter)
+	for rp.next() != waiter || !rp.fits(r) {
+		rp.cond.Wait()
+	}
+	rp.removeWaiter(waiter)
+	rp.used = rp.used.add(r)
+	// wake up the next waiter
+	rp.cond.Broadcast()
+	return r
+}
+
+// next returns the waiter which is allowed to acquire next
+func (rp *resourcePool) next() *poolWaiter {
+	var next *poolWaiter
+	for _, waiter := range rp.waiters {
+		if next == nil || waiter.before(next) {
+			next = waiter
+		}
+	}
+	return next
+}
+
+func (rp *resourcePool) removeWaiter(waiter *poolWaiter) {
+	for i, w := range rp.waiters {
+		if w == waiter {
+			rp.waiters = append(rp.waiters[:i], rp.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (rp *resourcePool) release(r Resources) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.used = rp.used.sub(r)
+	rp.cond.Broadcast()
+}
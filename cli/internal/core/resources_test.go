@@ -0,0 +1,141 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/util"
+	"gotest.tools/v3/assert"
+)
+
+func TestResourcePoolClamp(t *testing.T) {
+	pool := newResourcePool(Resources{CPU: 4, MemoryMB: 1000})
+	assert.Equal(t, pool.clamp(Resources{CPU: 8, MemoryMB: 4000}), Resources{CPU: 4, MemoryMB: 1000})
+	assert.Equal(t, pool.clamp(Resources{CPU: 2, MemoryMB: 500}), Resources{CPU: 2, MemoryMB: 500})
+
+	unlimitedMemory := newResourcePool(Resources{CPU: 4})
+	assert.Equal(t, unlimitedMemory.clamp(Resources{CPU: 1, MemoryMB: 4000}), Resources{CPU: 1})
+}
+
+func TestResourcePoolFIFO(t *testing.T) {
+	pool := newResourcePool(Resources{CPU: 2})
+	small := pool.acquire(Resources{CPU: 1}, 0)
+
+	// A large request waits for the small one to finish...
+	largeAcquired := make(chan struct{})
+	go func() {
+		pool.acquire(Resources{CPU: 2}, 0)
+		close(largeAcquired)
+	}()
+	// ...and a small request that arrives after it must wait behind it
+	for {
+		pool.mu.Lock()
+		waiting := len(pool.waiters) == 1
+		pool.mu.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	secondAcquired := make(chan struct{})
+	go func() {
+		pool.acquire(Resources{CPU: 1}, 0)
+		close(secondAcquired)
+	}()
+
+	select {
+	case <-secondAcquired:
+		t.Fatal("small request was admitted ahead of an earlier large request")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.release(small)
+	<-largeAcquired
+}
+
+func TestExecuteWithinBudget(t *testing.T) {
+	g := &dag.AcyclicGraph{}
+	for _, pkg := range []string{"a", "b", "c", "d"} {
+		g.Add(pkg)
+	}
+	p := NewScheduler(g)
+	p.AddTask(&Task{
+		Name:      "build",
+		TopoDeps:  make(util.Set),
+		Deps:      make(util.Set),
+		Resources: Resources{CPU: 2, MemoryMB: 3000},
+	})
+	p.AddTask(&Task{
+		Name:     "lint",
+		TopoDeps: make(util.Set),
+		Deps:     make(util.Set),
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"a", "b", "c", "d"},
+		TaskNames: []string{"build", "lint"},
+	})
+	assert.NilError(t, err, "Prepare")
+
+	mu := sync.Mutex{}
+	used := Resources{}
+	maxUsed := Resources{}
+	errs := p.Execute(func(taskID string) error {
+		resources := p.TaskResources(taskID)
+		mu.Lock()
+		used = used.add(resources)
+		if used.CPU > maxUsed.CPU {
+			maxUsed.CPU = used.CPU
+		}
+		if used.MemoryMB > maxUsed.MemoryMB {
+			maxUsed.MemoryMB = used.MemoryMB
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		used = used.sub(resources)
+		mu.Unlock()
+		return nil
+	}, ExecOpts{
+		Concurrency: 4,
+		MemoryMB:    4000,
+	})
+	assert.Equal(t, len(errs), 0)
+	assert.Assert(t, maxUsed.CPU <= 4, "used %v cpu", maxUsed.CPU)
+	// only one build fits in the memory budget at a time
+	assert.Assert(t, maxUsed.MemoryMB <= 3000, "used %v MB", maxUsed.MemoryMB)
+}
+
+func TestResourcePoolPriority(t *testing.T) {
+	pool := newResourcePool(Resources{CPU: 1})
+	first := pool.acquire(Resources{CPU: 1}, 0)
+
+	order := make(chan string, 2)
+	waitForWaiters := func(n int) {
+		for {
+			pool.mu.Lock()
+			waiting := len(pool.waiters) == n
+			pool.mu.Unlock()
+			if waiting {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	go func() {
+		acquired := pool.acquire(Resources{CPU: 1}, 1)
+		order <- "low"
+		pool.release(acquired)
+	}()
+	waitForWaiters(1)
+	go func() {
+		acquired := pool.acquire(Resources{CPU: 1}, 10)
+		order <- "high"
+		pool.release(acquired)
+	}()
+	waitForWaiters(2)
+
+	pool.release(first)
+	assert.Equal(t, <-order, "high")
+	assert.Equal(t, <-order, "low")
+}
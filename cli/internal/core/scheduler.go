@@ -3,7 +3,10 @@ package core
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/vercel/turborepo/cli/internal/util"
 
@@ -20,6 +23,13 @@ type Task struct {
 	Deps util.Set
 	// TopoDeps are dependencies across packages within the same topological graph (e.g. parent `build` -> child `build`) */
 	TopoDeps util.Set
+	// Persistent tasks never exit, so nothing may depend on them
+	Persistent bool
+	// Resources used by each instance of this task. If CPU is unset, it defaults to 1.
+	Resources Resources
+	// Variants are the names of the tasks that this task expands into. Running or
+	// depending on a task with variants runs each of the variants instead.
+	Variants []string
 }
 
 type Visitor = func(taskID string) error
@@ -70,32 +80,160 @@ func (p *Scheduler) Prepare(options *SchedulerExecutionOptions) error {
 		return err
 	}
 
+	return p.validatePersistentDependencies()
+}
+
+// validatePersistentDependencies returns an error if any task depends on a persistent task,
+// since the dependent task would wait forever for it to finish.
+func (p *Scheduler) validatePersistentDependencies() error {
+	for _, edge := range p.TaskGraph.Edges() {
+		dependencyID := dag.VertexName(edge.Target())
+		if dependencyID == ROOT_NODE_NAME {
+			continue
+		}
+		pkg, taskName := util.GetPackageTaskFromId(dependencyID)
+		task, err := p.getTaskDefinition(pkg, taskName, dependencyID)
+		if err != nil {
+			return err
+		}
+		if task.Persistent {
+			return fmt.Errorf("\"%v\" is a persistent task, \"%v\" cannot depend on it", dependencyID, dag.VertexName(edge.Source()))
+		}
+	}
 	return nil
 }
 
+// PersistentTasks returns the IDs of the persistent tasks in the task graph
+func (p *Scheduler) PersistentTasks() []string {
+	var persistent []string
+	for _, v := range p.TaskGraph.Vertices() {
+		taskID := dag.VertexName(v)
+		if taskID == ROOT_NODE_NAME {
+			continue
+		}
+		pkg, taskName := util.GetPackageTaskFromId(taskID)
+		if task, err := p.getTaskDefinition(pkg, taskName, taskID); err == nil && task.Persistent {
+			persistent = append(persistent, taskID)
+		}
+	}
+	sort.Strings(persistent)
+	return persistent
+}
+
 // ExecOpts controls a single walk of the task graph
 type ExecOpts struct {
 	// Parallel is whether to run tasks in parallel
 	Parallel bool
-	// Concurrency is the number of concurrent tasks that can be executed
+	// Concurrency is the CPU budget shared by executing tasks. Each task uses 1 unless
+	// it declares otherwise.
 	Concurrency int
+	// MemoryMB is the memory budget shared by executing tasks. 0 is unlimited.
+	MemoryMB int
+	// TaskDurations are estimates of how long tasks take, typically from previous runs.
+	// When more tasks are ready than fit in the budget, tasks on the longest remaining
+	// path through the graph start first. If nil, ready tasks start in the order they
+	// became ready.
+	TaskDurations map[string]time.Duration
+	// Skipped is called, once execution finishes, with each task which didn't run
+	// because one of its dependencies failed. It may be nil.
+	Skipped func(taskID string)
 }
 
 // Execute executes the pipeline, constructing an internal task graph and walking it accordingly.
 func (p *Scheduler) Execute(visitor Visitor, opts ExecOpts) []error {
-	var sema = util.NewSemaphore(opts.Concurrency)
-	return p.TaskGraph.Walk(func(v dag.Vertex) error {
+	pool := newResourcePool(Resources{CPU: opts.Concurrency, MemoryMB: opts.MemoryMB})
+	var priorities map[string]time.Duration
+	if opts.TaskDurations != nil {
+		priorities = p.CriticalPathPriorities(opts.TaskDurations)
+	}
+	var mu sync.Mutex
+	visited := make(util.Set)
+	errs := p.TaskGraph.Walk(func(v dag.Vertex) error {
+		taskID := dag.VertexName(v)
+		mu.Lock()
+		visited.Add(taskID)
+		mu.Unlock()
 		// Always return if it is the root node
-		if strings.Contains(dag.VertexName(v), ROOT_NODE_NAME) {
+		if strings.Contains(taskID, ROOT_NODE_NAME) {
 			return nil
 		}
-		// Acquire the semaphore unless parallel
+		// Acquire resources from the budget unless parallel
 		if !opts.Parallel {
-			sema.Acquire()
-			defer sema.Release()
+			acquired := pool.acquire(p.TaskResources(taskID), int64(priorities[taskID]))
+			defer pool.release(acquired)
 		}
-		return visitor(dag.VertexName(v))
+		return visitor(taskID)
 	})
+	if opts.Skipped != nil {
+		// The walk doesn't visit tasks whose dependencies failed
+		var skipped []string
+		for _, v := range p.TaskGraph.Vertices() {
+			taskID := dag.VertexName(v)
+			if !visited.Includes(taskID) && !strings.Contains(taskID, ROOT_NODE_NAME) {
+				skipped = append(skipped, taskID)
+			}
+		}
+		sort.Strings(skipped)
+		for _, taskID := range skipped {
+			opts.Skipped(taskID)
+		}
+	}
+	return errs
+}
+
+// CriticalPathPriorities returns, for each task, the estimated time from when it starts
+// until everything that depends on it has finished, assuming unlimited concurrency.
+// Tasks without a duration estimate are assumed to take the average of those with one.
+func (p *Scheduler) CriticalPathPriorities(durations map[string]time.Duration) map[string]time.Duration {
+	var defaultDuration time.Duration = 1
+	if len(durations) > 0 {
+		var total time.Duration
+		for _, duration := range durations {
+			total += duration
+		}
+		defaultDuration = total / time.Duration(len(durations))
+	}
+
+	priorities := make(map[string]time.Duration)
+	var visit func(taskID string) time.Duration
+	visit = func(taskID string) time.Duration {
+		if priority, ok := priorities[taskID]; ok {
+			return priority
+		}
+		var longestDependent time.Duration
+		// Up edges point from the tasks which depend on this one
+		for dependent := range p.TaskGraph.UpEdges(taskID) {
+			if priority := visit(dag.VertexName(dependent)); priority > longestDependent {
+				longestDependent = priority
+			}
+		}
+		duration, ok := durations[taskID]
+		if !ok {
+			duration = defaultDuration
+		}
+		priorities[taskID] = duration + longestDependent
+		return priorities[taskID]
+	}
+	for _, v := range p.TaskGraph.Vertices() {
+		if taskID := dag.VertexName(v); taskID != ROOT_NODE_NAME {
+			visit(taskID)
+		}
+	}
+	return priorities
+}
+
+// TaskResources returns the resources used by the given task
+func (p *Scheduler) TaskResources(taskID string) Resources {
+	pkg, taskName := util.GetPackageTaskFromId(taskID)
+	task, err := p.getTaskDefinition(pkg, taskName, taskID)
+	if err != nil {
+		return DefaultResources
+	}
+	resources := task.Resources
+	if resources.CPU == 0 {
+		resources.CPU = DefaultResources.CPU
+	}
+	return resources
 }
 
 func (p *Scheduler) getTaskDefinition(pkg string, taskName string, taskID string) (*Task, error) {
@@ -128,7 +266,7 @@ func (p *Scheduler) generateTaskGraph(pkgs []string, taskNames []string, tasksOn
 					// *are* required to have a definition.
 					continue
 				}
-				traversalQueue = append(traversalQueue, taskID)
+				traversalQueue = append(traversalQueue, p.expandVariants(taskID)...)
 			}
 		}
 	}
@@ -178,32 +316,36 @@ func (p *Scheduler) generateTaskGraph(pkgs []string, taskNames []string, tasksOn
 				for _, from := range task.TopoDeps.UnsafeListOfStrings() {
 					// add task dep from all the package deps within repo
 					for depPkg := range depPkgs {
-						fromTaskId := util.GetTaskId(depPkg, from)
-						p.TaskGraph.Add(fromTaskId)
-						p.TaskGraph.Add(toTaskId)
-						p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
-						traversalQueue = append(traversalQueue, fromTaskId)
+						for _, fromTaskId := range p.expandVariants(util.GetTaskId(depPkg, from)) {
+							p.TaskGraph.Add(fromTaskId)
+							p.TaskGraph.Add(toTaskId)
+							p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
+							traversalQueue = append(traversalQueue, fromTaskId)
+						}
 					}
 				}
 			}
 
 			if hasDeps {
 				for _, from := range deps.UnsafeListOfStrings() {
-					fromTaskId := util.GetTaskId(pkg, from)
-					p.TaskGraph.Add(fromTaskId)
-					p.TaskGraph.Add(toTaskId)
-					p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
-					traversalQueue = append(traversalQueue, fromTaskId)
+					for _, fromTaskId := range p.expandVariants(util.GetTaskId(pkg, from)) {
+						p.TaskGraph.Add(fromTaskId)
+						p.TaskGraph.Add(toTaskId)
+						p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
+						traversalQueue = append(traversalQueue, fromTaskId)
+					}
 				}
 			}
 
 			if hasPackageTaskDeps {
 				if pkgTaskDeps, ok := packageTasksDepsMap[toTaskId]; ok {
-					for _, fromTaskId := range pkgTaskDeps {
-						p.TaskGraph.Add(fromTaskId)
-						p.TaskGraph.Add(toTaskId)
-						p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
-						traversalQueue = append(traversalQueue, fromTaskId)
+					for _, pkgTaskDep := range pkgTaskDeps {
+						for _, fromTaskId := range p.expandVariants(pkgTaskDep) {
+							p.TaskGraph.Add(fromTaskId)
+							p.TaskGraph.Add(toTaskId)
+							p.TaskGraph.Connect(dag.BasicEdge(toTaskId, fromTaskId))
+							traversalQueue = append(traversalQueue, fromTaskId)
+						}
 					}
 				}
 			}
@@ -218,6 +360,21 @@ func (p *Scheduler) generateTaskGraph(pkgs []string, taskNames []string, tasksOn
 	return nil
 }
 
+// expandVariants returns the ids of the tasks that run for the given task id: the
+// task's variants if it has any, otherwise just the task itself
+func (p *Scheduler) expandVariants(taskID string) []string {
+	pkg, taskName := util.GetPackageTaskFromId(taskID)
+	task, err := p.getTaskDefinition(pkg, taskName, taskID)
+	if err != nil || len(task.Variants) == 0 {
+		return []string{taskID}
+	}
+	taskIDs := make([]string, len(task.Variants))
+	for i, variant := range task.Variants {
+		taskIDs[i] = util.GetTaskId(pkg, variant)
+	}
+	return taskIDs
+}
+
 func getPackageTaskDepsMap(packageTaskDeps [][]string) map[string][]string {
 	depMap := make(map[string][]string)
 	for _, packageTaskDep := range packageTaskDeps {
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/vercel/turborepo/cli/internal/util"
 	"gotest.tools/v3/assert"
@@ -80,6 +83,95 @@ func TestSchedulerDefault(t *testing.T) {
 	}
 }
 
+func TestDependOnPersistentTask(t *testing.T) {
+	g := &dag.AcyclicGraph{}
+	g.Add("app")
+	g.Add("lib")
+	g.Connect(dag.BasicEdge("app", "lib"))
+
+	p := NewScheduler(g)
+	topoDeps := make(util.Set)
+	topoDeps.Add("dev")
+	p.AddTask(&Task{
+		Name:       "dev",
+		TopoDeps:   topoDeps,
+		Deps:       make(util.Set),
+		Persistent: true,
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"app", "lib"},
+		TaskNames: []string{"dev"},
+	})
+	assert.ErrorContains(t, err, "\"lib#dev\" is a persistent task, \"app#dev\" cannot depend on it")
+}
+
+func TestPersistentTasks(t *testing.T) {
+	g := &dag.AcyclicGraph{}
+	g.Add("app")
+	g.Add("lib")
+	g.Connect(dag.BasicEdge("app", "lib"))
+
+	p := NewScheduler(g)
+	buildDeps := make(util.Set)
+	buildDeps.Add("build")
+	p.AddTask(&Task{
+		Name:     "build",
+		TopoDeps: buildDeps,
+		Deps:     make(util.Set),
+	})
+	p.AddTask(&Task{
+		Name:       "dev",
+		TopoDeps:   buildDeps,
+		Deps:       make(util.Set),
+		Persistent: true,
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"app", "lib"},
+		TaskNames: []string{"dev"},
+	})
+	assert.NilError(t, err, "Prepare")
+	assert.DeepEqual(t, p.PersistentTasks(), []string{"app#dev", "lib#dev"})
+}
+
+func TestCriticalPathPriorities(t *testing.T) {
+	// app -> lib -> util
+	// docs
+	g := &dag.AcyclicGraph{}
+	g.Add("app")
+	g.Add("lib")
+	g.Add("util")
+	g.Add("docs")
+	g.Connect(dag.BasicEdge("app", "lib"))
+	g.Connect(dag.BasicEdge("lib", "util"))
+
+	p := NewScheduler(g)
+	topoDeps := make(util.Set)
+	topoDeps.Add("build")
+	p.AddTask(&Task{
+		Name:     "build",
+		TopoDeps: topoDeps,
+		Deps:     make(util.Set),
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"app", "lib", "util", "docs"},
+		TaskNames: []string{"build"},
+	})
+	assert.NilError(t, err, "Prepare")
+
+	priorities := p.CriticalPathPriorities(map[string]time.Duration{
+		"app#build":  10 * time.Second,
+		"lib#build":  20 * time.Second,
+		"util#build": 30 * time.Second,
+	})
+	assert.DeepEqual(t, priorities, map[string]time.Duration{
+		"app#build":  10 * time.Second,
+		"lib#build":  30 * time.Second,
+		"util#build": 60 * time.Second,
+		// no history, so it is assumed to take the average
+		"docs#build": 20 * time.Second,
+	})
+}
+
 func TestUnknownDependency(t *testing.T) {
 	g := &dag.AcyclicGraph{}
 	g.Add("a")
@@ -209,6 +301,55 @@ libA#build
 	assert.Equal(t, expected, actual)
 }
 
+func TestTaskVariants(t *testing.T) {
+	graph := &dag.AcyclicGraph{}
+	graph.Add("app")
+	graph.Add("lib")
+	graph.Connect(dag.BasicEdge("app", "lib"))
+
+	p := NewScheduler(graph)
+	p.AddTask(&Task{
+		Name:     "build",
+		Variants: []string{"build@node", "build@browser"},
+	})
+	p.AddTask(&Task{Name: "build@node"})
+	p.AddTask(&Task{Name: "build@browser"})
+	// depends on every variant of build in its dependencies
+	p.AddTask(&Task{
+		Name:     "test",
+		TopoDeps: util.SetFromStrings([]string{"build"}),
+	})
+	// depends on a single variant of build in its own package
+	p.AddTask(&Task{
+		Name: "serve",
+		Deps: util.SetFromStrings([]string{"build@node"}),
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"app", "lib"},
+		TaskNames: []string{"test", "serve"},
+	})
+	assert.NilError(t, err, "Prepare")
+	actual := strings.TrimSpace(p.TaskGraph.String())
+	expected := strings.TrimSpace(`
+___ROOT___
+app#build@node
+  ___ROOT___
+app#serve
+  app#build@node
+app#test
+  lib#build@browser
+  lib#build@node
+lib#build@browser
+  ___ROOT___
+lib#build@node
+  ___ROOT___
+lib#serve
+  lib#build@node
+lib#test
+  ___ROOT___`)
+	assert.Equal(t, expected, actual)
+}
+
 func TestRunWithNoTasksFound(t *testing.T) {
 	graph := &dag.AcyclicGraph{}
 	graph.Add("app")
@@ -479,3 +620,46 @@ b#test
 c#test
   ___ROOT___
 `
+
+func TestFailedDependencies(t *testing.T) {
+	graph := &dag.AcyclicGraph{}
+	graph.Add("app1")
+	graph.Add("libA")
+	graph.Add("libB")
+	graph.Connect(dag.BasicEdge("app1", "libA"))
+
+	dependOnBuild := make(util.Set)
+	dependOnBuild.Add("build")
+	p := NewScheduler(graph)
+	p.AddTask(&Task{
+		Name:     "build",
+		TopoDeps: dependOnBuild,
+		Deps:     make(util.Set),
+	})
+	err := p.Prepare(&SchedulerExecutionOptions{
+		Packages:  []string{"app1", "libA", "libB"},
+		TaskNames: []string{"build"},
+	})
+	assert.NilError(t, err, "Prepare")
+
+	ran := &sync.Map{}
+	var skipped []string
+	errs := p.Execute(func(taskID string) error {
+		ran.Store(taskID, true)
+		if taskID == "libA#build" {
+			return errors.New("libA failed")
+		}
+		return nil
+	}, ExecOpts{
+		Concurrency: 10,
+		Skipped: func(taskID string) {
+			skipped = append(skipped, taskID)
+		},
+	})
+	assert.Equal(t, len(errs), 1)
+	assert.DeepEqual(t, skipped, []string{"app1#build"})
+	_, ranLibB := ran.Load("libB#build")
+	assert.Assert(t, ranLibB, "expected independent task libB#build to run")
+	_, ranApp1 := ran.Load("app1#build")
+	assert.Assert(t, !ranApp1, "expected app1#build to be skipped")
+}
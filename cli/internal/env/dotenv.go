@@ -0,0 +1,121 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParseDotEnv parses the contents of a .env file into a map of variables.
+// It supports comments, blank lines, an optional leading `export`, and
+// single or double quoted values. Double quoted values may span lines and
+// support the \n, \r, \t, \" and \\ escapes. Variable expansion is not supported.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("line %v: expected KEY=VALUE", lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %v: invalid variable name %q", lineNumber, key)
+		}
+		value := strings.TrimSpace(line[i+1:])
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %v: unterminated single quoted value for %v", lineNumber, key)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// keep reading lines until we find the closing quote
+			raw := value[1:]
+			for {
+				if end := closingQuote(raw); end >= 0 {
+					raw = raw[:end]
+					break
+				}
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %v: unterminated double quoted value for %v", lineNumber, key)
+				}
+				lineNumber++
+				raw += "\n" + scanner.Text()
+			}
+			value = unescapeDoubleQuoted(raw)
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the first unescaped double quote in s, or -1
+func closingQuote(s string) int {
+	escaped := false
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return i
+		}
+	}
+	return -1
+}
+
+var _doubleQuotedEscapes = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescapeDoubleQuoted(s string) string {
+	return _doubleQuotedEscapes.Replace(s)
+}
+
+// GetDotEnvPairs reads the given .env files, relative to dir, and returns the
+// key=value pairs they define. Files are applied in order, so variables in later
+// files take precedence over earlier ones. Files that don't exist are skipped.
+func GetDotEnvPairs(dir string, files []string) ([]string, error) {
+	merged := make(map[string]string)
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		vars, err := ParseDotEnv(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %v: %w", file, err)
+		}
+		for key, value := range vars {
+			merged[key] = value
+		}
+	}
+	pairs := make([]string, 0, len(merged))
+	for key, value := range merged {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
@@ -0,0 +1,81 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	contents := strings.Join([]string{
+		"# a comment",
+		"",
+		"PLAIN=value",
+		"export EXPORTED=yes",
+		"SPACED = around equals ",
+		"INLINE=value # trailing comment",
+		"HASH=no#comment",
+		"SINGLE='literal $HOME \\n'",
+		`DOUBLE="line one\nline two \"quoted\""`,
+		`MULTI="first`,
+		`second"`,
+		"EMPTY=",
+	}, "\n")
+	got, err := ParseDotEnv(strings.NewReader(contents))
+	if err != nil {
+		t.Fatalf("ParseDotEnv() error = %v", err)
+	}
+	want := map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "yes",
+		"SPACED":   "around equals",
+		"INLINE":   "value",
+		"HASH":     "no#comment",
+		"SINGLE":   "literal $HOME \\n",
+		"DOUBLE":   "line one\nline two \"quoted\"",
+		"MULTI":    "first\nsecond",
+		"EMPTY":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotEnv() got %v, want %v", got, want)
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"missing equals", "NOPE"},
+		{"invalid key", "MY KEY=value"},
+		{"unterminated single quote", "KEY='value"},
+		{"unterminated double quote", "KEY=\"value\nmore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDotEnv(strings.NewReader(tt.contents)); err == nil {
+				t.Errorf("ParseDotEnv() expected an error for %q", tt.contents)
+			}
+		})
+	}
+}
+
+func TestGetDotEnvPairs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("A=base\nB=base\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env.local"), []byte("B=local\nC=local\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env.local: %v", err)
+	}
+	got, err := GetDotEnvPairs(dir, []string{".env", ".env.missing", ".env.local"})
+	if err != nil {
+		t.Fatalf("GetDotEnvPairs() error = %v", err)
+	}
+	want := []string{"A=base", "B=local", "C=local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDotEnvPairs() got %v, want %v", got, want)
+	}
+}
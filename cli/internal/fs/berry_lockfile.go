@@ -0,0 +1,452 @@
+package fs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const berryMetadataKey = "__metadata"
+
+// BerryLockfileEntry is a single resolution in a yarn v2+ (berry) lockfile. Field order
+// matches the order yarn itself writes them in.
+type BerryLockfileEntry struct {
+	Version              string                     `yaml:"version"`
+	Resolution           string                     `yaml:"resolution"`
+	Dependencies         map[string]string          `yaml:"dependencies,omitempty"`
+	PeerDependencies     map[string]string          `yaml:"peerDependencies,omitempty"`
+	DependenciesMeta     map[string]map[string]bool `yaml:"dependenciesMeta,omitempty"`
+	PeerDependenciesMeta map[string]map[string]bool `yaml:"peerDependenciesMeta,omitempty"`
+	Bin                  map[string]string          `yaml:"bin,omitempty"`
+	Checksum             string                     `yaml:"checksum,omitempty"`
+	Conditions           string                     `yaml:"conditions,omitempty"`
+	LanguageName         string                     `yaml:"languageName,omitempty"`
+	LinkType             string                     `yaml:"linkType,omitempty"`
+}
+
+type berryLockfileMetadata struct {
+	Version  string `yaml:"version"`
+	CacheKey string `yaml:"cacheKey"`
+}
+
+// BerryLockfile is an in-memory representation of a yarn v2+ lockfile. Unlike the
+// yarn v1 lockfile, the berry lockfile is valid YAML, and every descriptor in it carries
+// an explicit protocol (npm:, workspace:, patch:, portal:, ...).
+type BerryLockfile struct {
+	metadata berryLockfileMetadata
+	// descriptor -> entry. Several descriptors may share a single entry
+	entries map[string]*BerryLockfileEntry
+	// repo-relative unix path -> workspace descriptor
+	workspaces map[string]string
+	// bare descriptor (without bind parameters) -> descriptor as it appears in the lockfile
+	unbound map[string]string
+	// descriptor -> builtin patches (e.g. ~builtin<compat/resolve>) that yarn applies to it
+	builtinPatches map[string][]string
+	// resolutions from the root package.json, applied before looking up descriptors
+	resolutions []berryResolution
+}
+
+// ReadBerryLockfile reads and parses the yarn v2+ lockfile at the given path.
+// resolutions are the contents of the "resolutions" field in the root package.json.
+func ReadBerryLockfile(path AbsolutePath, resolutions map[string]string) (*BerryLockfile, error) {
+	contents, err := ioutil.ReadFile(path.ToString())
+	if err != nil {
+		return nil, fmt.Errorf("reading yarn.lock: %w", err)
+	}
+	return ParseBerryLockfile(contents, resolutions)
+}
+
+// ParseBerryLockfile parses the contents of a yarn v2+ lockfile
+func ParseBerryLockfile(contents []byte, resolutions map[string]string) (*BerryLockfile, error) {
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("could not unmarshal lockfile: %w", err)
+	}
+	lockfile := newBerryLockfile()
+	for key, node := range raw {
+		if key == berryMetadataKey {
+			if err := node.Decode(&lockfile.metadata); err != nil {
+				return nil, fmt.Errorf("could not read lockfile metadata: %w", err)
+			}
+			continue
+		}
+		entry := &BerryLockfileEntry{}
+		if err := node.Decode(entry); err != nil {
+			return nil, fmt.Errorf("could not unmarshal lockfile entry %v: %w", key, err)
+		}
+		// Descriptors which resolve to the same package are grouped under a single key
+		// (e.g. "js-tokens@npm:^3.0.0 || ^4.0.0, js-tokens@npm:^4.0.0")
+		for _, descriptor := range strings.Split(key, ",") {
+			lockfile.add(strings.TrimSpace(descriptor), entry)
+		}
+	}
+	selectors := make([]string, 0, len(resolutions))
+	for selector := range resolutions {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+	for _, selector := range selectors {
+		resolution, err := parseBerryResolution(selector, resolutions[selector])
+		if err != nil {
+			return nil, err
+		}
+		lockfile.resolutions = append(lockfile.resolutions, resolution)
+	}
+	// More specific resolutions (those that specify a parent or a range) win over bare package names
+	sort.SliceStable(lockfile.resolutions, func(i, j int) bool {
+		return lockfile.resolutions[i].specificity() > lockfile.resolutions[j].specificity()
+	})
+	return lockfile, nil
+}
+
+func newBerryLockfile() *BerryLockfile {
+	return &BerryLockfile{
+		entries:        make(map[string]*BerryLockfileEntry),
+		workspaces:     make(map[string]string),
+		unbound:        make(map[string]string),
+		builtinPatches: make(map[string][]string),
+	}
+}
+
+func (l *BerryLockfile) add(descriptor string, entry *BerryLockfileEntry) {
+	l.entries[descriptor] = entry
+	l.unbound[stripBindings(descriptor)] = descriptor
+	name, protocol, ref := parseBerryDescriptor(descriptor)
+	if protocol == "workspace" && entry.Resolution == descriptor {
+		l.workspaces[ref] = descriptor
+	} else if protocol == "patch" {
+		source, patchPaths := splitPatchReference(ref)
+		if isBuiltinPatch(patchPaths) {
+			inner := patchSourceDescriptor(name, source)
+			l.builtinPatches[inner] = append(l.builtinPatches[inner], descriptor)
+		}
+	}
+}
+
+// Entry returns the lockfile entry for the given descriptor, as it appears in the lockfile
+func (l *BerryLockfile) Entry(descriptor string) (*BerryLockfileEntry, bool) {
+	entry, ok := l.entries[descriptor]
+	return entry, ok
+}
+
+// WorkspaceDescriptor returns the descriptor of the workspace located at the given repo-relative
+// directory. The root workspace is located at ".".
+func (l *BerryLockfile) WorkspaceDescriptor(dir string) (string, bool) {
+	descriptor, ok := l.workspaces[filepath.ToSlash(dir)]
+	return descriptor, ok
+}
+
+// ResolveDescriptor finds the lockfile descriptor that yarn would use for the dependency
+// name@version, declared by the package parent. It applies resolutions from the root
+// package.json, and the default "npm:" protocol for bare semver ranges.
+func (l *BerryLockfile) ResolveDescriptor(parent string, name string, version string) (string, bool) {
+	candidates := []string{}
+	for _, resolution := range l.resolutions {
+		if resolution.matches(parent, name, version) {
+			candidates = append(candidates, formatBerryDescriptor(name, resolution.override))
+			break
+		}
+	}
+	// Fall back to the descriptor as written for references yarn doesn't normalize (urls, etc.)
+	candidates = append(candidates, formatBerryDescriptor(name, version), fmt.Sprintf("%v@%v", name, version))
+	for _, candidate := range candidates {
+		if _, ok := l.entries[candidate]; ok {
+			return candidate, true
+		}
+		// patch: and portal: descriptors are bound to the workspace that declared them, so
+		// the key in the lockfile has extra "::locator=..." parameters we can't recompute
+		if descriptor, ok := l.unbound[stripBindings(candidate)]; ok {
+			return descriptor, true
+		}
+	}
+	return "", false
+}
+
+// DescriptorDependencies returns any lockfile descriptors that must be kept alongside the given
+// descriptor, in addition to the dependencies of its entry. A patched package requires the entry
+// for the original package, and yarn automatically applies its builtin patches to some packages
+// (e.g. resolve, typescript), which have their own entries.
+func (l *BerryLockfile) DescriptorDependencies(descriptor string) []string {
+	name, protocol, ref := parseBerryDescriptor(descriptor)
+	if protocol != "patch" {
+		return l.builtinPatches[descriptor]
+	}
+	source, _ := splitPatchReference(ref)
+	inner := patchSourceDescriptor(name, source)
+	if _, ok := l.entries[inner]; ok {
+		return []string{inner}
+	}
+	return nil
+}
+
+// PatchFiles returns the repo-relative paths of any patch files referenced by the given
+// descriptors. Builtin patches that ship with yarn are not included.
+func (l *BerryLockfile) PatchFiles(descriptors []string) []string {
+	patchSet := make(map[string]struct{})
+	for _, descriptor := range descriptors {
+		_, protocol, ref := parseBerryDescriptor(descriptor)
+		if protocol != "patch" {
+			continue
+		}
+		_, patchPaths := splitPatchReference(ref)
+		locatorDir := "."
+		if locator, ok := bindingParam(descriptor, "locator"); ok {
+			if _, locatorProtocol, locatorRef := parseBerryDescriptor(locator); locatorProtocol == "workspace" {
+				locatorDir = locatorRef
+			}
+		}
+		for _, patchPath := range patchPaths {
+			if isBuiltinPatch([]string{patchPath}) {
+				continue
+			}
+			patchSet[filepath.ToSlash(filepath.Join(locatorDir, patchPath))] = struct{}{}
+		}
+	}
+	patches := make([]string, 0, len(patchSet))
+	for patch := range patchSet {
+		patches = append(patches, patch)
+	}
+	sort.Strings(patches)
+	return patches
+}
+
+// Subset returns a new lockfile containing only the given descriptors
+func (l *BerryLockfile) Subset(descriptors []string) *BerryLockfile {
+	subset := newBerryLockfile()
+	subset.metadata = l.metadata
+	subset.resolutions = l.resolutions
+	for _, descriptor := range descriptors {
+		if entry, ok := l.entries[descriptor]; ok {
+			subset.add(descriptor, entry)
+		}
+	}
+	return subset
+}
+
+// Encode writes the lockfile in the format yarn expects, grouping descriptors that share
+// an entry under a single key.
+func (l *BerryLockfile) Encode(w io.Writer) error {
+	grouped := make(map[*BerryLockfileEntry][]string)
+	for descriptor, entry := range l.entries {
+		grouped[entry] = append(grouped[entry], descriptor)
+	}
+	keys := make([]string, 0, len(grouped))
+	keyEntries := make(map[string]*BerryLockfileEntry, len(grouped))
+	for entry, descriptors := range grouped {
+		sort.Strings(descriptors)
+		key := strings.Join(descriptors, ", ")
+		keys = append(keys, key)
+		keyEntries[key] = entry
+	}
+	sort.Strings(keys)
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString("# This file is generated by running \"yarn install\" inside your project.\n# Manual changes might be lost - proceed with caution!\n\n")
+	writer.WriteString(fmt.Sprintf("%v:\n  version: %v\n", berryMetadataKey, l.metadata.Version))
+	if l.metadata.CacheKey != "" {
+		writer.WriteString(fmt.Sprintf("  cacheKey: %v\n", l.metadata.CacheKey))
+	}
+	for _, key := range keys {
+		var b strings.Builder
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(keyEntries[key]); err != nil {
+			return fmt.Errorf("failed to encode lockfile entry %v: %w", key, err)
+		}
+		writer.WriteString(fmt.Sprintf("\n%q:\n", key))
+		for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+			writer.WriteString("  " + line + "\n")
+		}
+	}
+	return writer.Flush()
+}
+
+// berryResolution is a parsed entry from the "resolutions" field of the root package.json.
+// See https://yarnpkg.com/configuration/manifest#resolutions
+type berryResolution struct {
+	parent   string
+	name     string
+	version  string
+	override string
+}
+
+func parseBerryResolution(selector string, override string) (berryResolution, error) {
+	resolution := berryResolution{override: override}
+	selector = strings.TrimPrefix(selector, "**/")
+	// The last segment is the package being overridden. Scoped packages contain a slash themselves.
+	segments := strings.Split(selector, "/")
+	last := segments[len(segments)-1]
+	rest := segments[:len(segments)-1]
+	if len(rest) > 0 && strings.HasPrefix(rest[len(rest)-1], "@") {
+		last = rest[len(rest)-1] + "/" + last
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) > 0 {
+		resolution.parent = strings.Join(rest, "/")
+	}
+	if at := strings.LastIndex(last, "@"); at > 0 {
+		resolution.name = last[:at]
+		resolution.version = last[at+1:]
+	} else {
+		resolution.name = last
+	}
+	if resolution.name == "" {
+		return berryResolution{}, fmt.Errorf("invalid resolution %q", selector)
+	}
+	return resolution, nil
+}
+
+func (r berryResolution) specificity() int {
+	specificity := 0
+	if r.parent != "" {
+		specificity += 2
+	}
+	if r.version != "" {
+		specificity++
+	}
+	return specificity
+}
+
+func (r berryResolution) matches(parent string, name string, version string) bool {
+	if r.name != name {
+		return false
+	}
+	if r.parent != "" && r.parent != parent {
+		return false
+	}
+	if r.version != "" && withDefaultProtocol(r.version) != withDefaultProtocol(version) {
+		return false
+	}
+	return true
+}
+
+// parseBerryDescriptor splits a descriptor such as "lodash@npm:^4.17.21" into its
+// name ("lodash"), protocol ("npm") and reference ("^4.17.21"). Bind parameters
+// ("::locator=...") are dropped from the reference.
+func parseBerryDescriptor(descriptor string) (string, string, string) {
+	descriptor = stripBindings(descriptor)
+	// scoped packages start with an "@", skip it when looking for the separator
+	start := 0
+	if strings.HasPrefix(descriptor, "@") {
+		start = 1
+	}
+	at := strings.Index(descriptor[start:], "@")
+	if at == -1 {
+		return "", "", descriptor
+	}
+	at += start
+	name := descriptor[:at]
+	rest := descriptor[at+1:]
+	protocol, ref := parseDependencyProtocol(rest)
+	return name, protocol, ref
+}
+
+func formatBerryDescriptor(name string, version string) string {
+	protocol, ref := parseDependencyProtocol(version)
+	if protocol == "patch" {
+		// The package being patched is embedded in the reference, with the characters that
+		// are significant to yarn escaped: "patch:lodash@npm%3A4.17.21#./lodash.patch"
+		source, patchPaths := ref, ""
+		if hash := strings.Index(ref, "#"); hash != -1 {
+			source, patchPaths = ref[:hash], ref[hash:]
+		}
+		if at := strings.LastIndex(source, "@"); at > 0 {
+			source = fmt.Sprintf("%v@%v", source[:at], withDefaultProtocol(source[at+1:]))
+		}
+		return fmt.Sprintf("%v@patch:%v%v", name, escapeBerryReference(source), patchPaths)
+	}
+	return fmt.Sprintf("%v@%v", name, withDefaultProtocol(version))
+}
+
+func escapeBerryReference(ref string) string {
+	ref = strings.ReplaceAll(ref, "%", "%25")
+	ref = strings.ReplaceAll(ref, ":", "%3A")
+	return strings.ReplaceAll(ref, "#", "%23")
+}
+
+// withDefaultProtocol adds the "npm:" protocol to ranges that don't specify one
+func withDefaultProtocol(version string) string {
+	if protocol, _ := parseDependencyProtocol(version); protocol == "" {
+		return "npm:" + version
+	}
+	return version
+}
+
+// parseDependencyProtocol splits "npm:^1.2.3" into "npm" and "^1.2.3". Versions without a
+// protocol, including urls, are returned with an empty protocol.
+func parseDependencyProtocol(version string) (string, string) {
+	colon := strings.Index(version, ":")
+	if colon == -1 || strings.HasPrefix(version[colon:], "://") {
+		return "", version
+	}
+	protocol := version[:colon]
+	if strings.ContainsAny(protocol, "/@ ") {
+		return "", version
+	}
+	return protocol, version[colon+1:]
+}
+
+func stripBindings(descriptor string) string {
+	if idx := strings.Index(descriptor, "::"); idx != -1 {
+		return descriptor[:idx]
+	}
+	return descriptor
+}
+
+func bindingParam(descriptor string, param string) (string, bool) {
+	idx := strings.Index(descriptor, "::")
+	if idx == -1 {
+		return "", false
+	}
+	values, err := url.ParseQuery(descriptor[idx+2:])
+	if err != nil {
+		return "", false
+	}
+	value := values.Get(param)
+	return value, value != ""
+}
+
+// splitPatchReference splits the reference of a patch: descriptor
+// ("lodash@npm%3A4.17.21#./patches/lodash.patch") into the source descriptor and
+// the list of patch files applied to it
+func splitPatchReference(ref string) (string, []string) {
+	hash := strings.Index(ref, "#")
+	if hash == -1 {
+		source, _ := url.PathUnescape(ref)
+		return source, nil
+	}
+	source, err := url.PathUnescape(ref[:hash])
+	if err != nil {
+		source = ref[:hash]
+	}
+	return source, strings.Split(ref[hash+1:], "&")
+}
+
+// patchSourceDescriptor returns the descriptor of the package that a patch applies to. Older
+// lockfiles omit the protocol for npm packages ("resolve@patch:resolve@^1.20.0#...")
+func patchSourceDescriptor(name string, source string) string {
+	sourceName, sourceProtocol, sourceRef := parseBerryDescriptor(source)
+	if sourceName == "" {
+		sourceName = name
+	}
+	if sourceProtocol == "" {
+		sourceProtocol = "npm"
+	}
+	return fmt.Sprintf("%v@%v:%v", sourceName, sourceProtocol, sourceRef)
+}
+
+func isBuiltinPatch(patchPaths []string) bool {
+	for _, patchPath := range patchPaths {
+		if !strings.HasPrefix(patchPath, "~builtin<") && !strings.HasPrefix(patchPath, "optional!builtin<") {
+			return false
+		}
+	}
+	return len(patchPaths) > 0
+}
@@ -0,0 +1,138 @@
+package fs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func getBerryLockfile(t *testing.T, resolutions map[string]string) *BerryLockfile {
+	t.Helper()
+	contents, err := os.ReadFile(filepath.Join("testdata", "berry.lock"))
+	if err != nil {
+		t.Fatalf("reading berry.lock: %v", err)
+	}
+	lockfile, err := ParseBerryLockfile(contents, resolutions)
+	if err != nil {
+		t.Fatalf("parsing berry.lock: %v", err)
+	}
+	return lockfile
+}
+
+func Test_BerryLockfileResolveDescriptor(t *testing.T) {
+	lockfile := getBerryLockfile(t, map[string]string{"lodash": "4.17.20"})
+
+	testCases := []struct {
+		name       string
+		parent     string
+		dependency string
+		version    string
+		want       string
+	}{
+		{
+			name:       "default npm protocol",
+			parent:     "ui",
+			dependency: "is-number",
+			version:    "^6.0.0",
+			want:       "is-number@npm:^6.0.0",
+		},
+		{
+			name:       "workspace alias",
+			parent:     "web",
+			dependency: "ui",
+			version:    "workspace:*",
+			want:       "ui@workspace:*",
+		},
+		{
+			name:       "resolution override",
+			parent:     "docs",
+			dependency: "lodash",
+			version:    "^4.17.21",
+			want:       "lodash@npm:4.17.20",
+		},
+		{
+			name:       "patch bound to a locator",
+			parent:     "web",
+			dependency: "is-odd",
+			version:    "patch:is-odd@npm:3.0.1#./.yarn/patches/is-odd-npm-3.0.1-93c3c3f41b.patch",
+			want:       "is-odd@patch:is-odd@npm%3A3.0.1#./.yarn/patches/is-odd-npm-3.0.1-93c3c3f41b.patch::version=3.0.1&hash=9a1b2c&locator=web%40workspace%3Aapps%2Fweb",
+		},
+		{
+			name:       "portal",
+			parent:     "web",
+			dependency: "local-lib",
+			version:    "portal:../local-lib",
+			want:       "local-lib@portal:../local-lib::locator=web%40workspace%3Aapps%2Fweb",
+		},
+	}
+	for _, tc := range testCases {
+		got, ok := lockfile.ResolveDescriptor(tc.parent, tc.dependency, tc.version)
+		assert.Assert(t, ok, "%v: descriptor not found", tc.name)
+		assert.Equal(t, got, tc.want, tc.name)
+	}
+
+	_, ok := lockfile.ResolveDescriptor("docs", "left-pad", "^1.0.0")
+	assert.Assert(t, !ok, "expected missing dependency to not resolve")
+}
+
+func Test_BerryLockfileResolutionSpecificity(t *testing.T) {
+	lockfile := getBerryLockfile(t, map[string]string{
+		"lodash":      "4.17.21",
+		"docs/lodash": "4.17.20",
+	})
+	got, ok := lockfile.ResolveDescriptor("docs", "lodash", "^4.17.0")
+	assert.Assert(t, ok)
+	assert.Equal(t, got, "lodash@npm:4.17.20")
+}
+
+func Test_BerryLockfilePatches(t *testing.T) {
+	lockfile := getBerryLockfile(t, nil)
+
+	patched := "is-odd@patch:is-odd@npm%3A3.0.1#./.yarn/patches/is-odd-npm-3.0.1-93c3c3f41b.patch::version=3.0.1&hash=9a1b2c&locator=web%40workspace%3Aapps%2Fweb"
+	assert.DeepEqual(t, lockfile.DescriptorDependencies(patched), []string{"is-odd@npm:3.0.1"})
+	assert.DeepEqual(t, lockfile.DescriptorDependencies("resolve@npm:^1.20.0"), []string{"resolve@patch:resolve@^1.20.0#~builtin<compat/resolve>"})
+	assert.DeepEqual(t, lockfile.PatchFiles([]string{patched, "resolve@patch:resolve@^1.20.0#~builtin<compat/resolve>"}), []string{"apps/web/.yarn/patches/is-odd-npm-3.0.1-93c3c3f41b.patch"})
+}
+
+func Test_BerryLockfileWorkspaces(t *testing.T) {
+	lockfile := getBerryLockfile(t, nil)
+
+	root, ok := lockfile.WorkspaceDescriptor(".")
+	assert.Assert(t, ok)
+	assert.Equal(t, root, "berry-patch@workspace:.")
+	ui, ok := lockfile.WorkspaceDescriptor(filepath.Join("packages", "ui"))
+	assert.Assert(t, ok)
+	assert.Equal(t, ui, "ui@workspace:packages/ui")
+}
+
+func Test_BerryLockfileSubsetRoundTrip(t *testing.T) {
+	lockfile := getBerryLockfile(t, nil)
+	subset := lockfile.Subset([]string{
+		"berry-patch@workspace:.",
+		"ui@workspace:*",
+		"ui@workspace:packages/ui",
+		"resolve@npm:^1.20.0",
+		"resolve@patch:resolve@^1.20.0#~builtin<compat/resolve>",
+		"is-core-module@npm:^2.9.0",
+		"not-in-lockfile@npm:1.0.0",
+	})
+
+	var b bytes.Buffer
+	if err := subset.Encode(&b); err != nil {
+		t.Fatalf("encoding lockfile: %v", err)
+	}
+	reparsed, err := ParseBerryLockfile(b.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parsing encoded lockfile: %v\n%v", err, b.String())
+	}
+	assert.Equal(t, reparsed.metadata, lockfile.metadata)
+	assert.Equal(t, len(reparsed.entries), 6)
+	entry, ok := reparsed.Entry("resolve@npm:^1.20.0")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, entry, lockfile.entries["resolve@npm:^1.20.0"])
+	// descriptors sharing an entry are written under a single key
+	assert.Assert(t, bytes.Contains(b.Bytes(), []byte("\n\"ui@workspace:*, ui@workspace:packages/ui\":\n")), b.String())
+}
@@ -0,0 +1,155 @@
+package fs
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/doublestar"
+)
+
+const (
+	// DefaultInputsToken in a task's inputs stands for the files that would be hashed
+	// if no inputs were specified, i.e. every file in the package
+	DefaultInputsToken = "$TURBO_DEFAULT$"
+	// RootInputPrefix anchors an input pattern at the repository root rather than the package
+	RootInputPrefix = "$ROOT/"
+	// NegatedInputPrefix excludes files matching the rest of the pattern
+	NegatedInputPrefix = "!"
+)
+
+// InputPatterns is the parsed form of a task's inputs. All patterns are unix-style
+// globs relative to the package directory. Patterns for files outside of the package
+// begin with "../".
+type InputPatterns struct {
+	// IncludeDefault is true if every file in the package is an input, before exclusions
+	IncludeDefault bool
+	Include        []string
+	Exclude        []string
+	// pkgDir is the repo-relative unix path of the package
+	pkgDir string
+}
+
+// ParseInputPatterns converts the inputs of a task in the package at packagePath,
+// relative to the repository root, into package-relative patterns.
+// If no inclusions are specified, every file in the package is included.
+func ParseInputPatterns(packagePath string, patterns []string) InputPatterns {
+	pkgDir := path.Clean(filepath.ToSlash(packagePath))
+	parsed := InputPatterns{pkgDir: pkgDir}
+	for _, pattern := range patterns {
+		if pattern == DefaultInputsToken {
+			parsed.IncludeDefault = true
+			continue
+		}
+		negated := strings.HasPrefix(pattern, NegatedInputPrefix)
+		pattern = filepath.ToSlash(strings.TrimPrefix(pattern, NegatedInputPrefix))
+		if strings.HasPrefix(pattern, RootInputPrefix) {
+			pattern = relativeToPackage(pkgDir, strings.TrimPrefix(pattern, RootInputPrefix))
+		} else {
+			pattern = path.Clean(pattern)
+		}
+		if negated {
+			parsed.Exclude = append(parsed.Exclude, pattern)
+		} else {
+			parsed.Include = append(parsed.Include, pattern)
+		}
+	}
+	if len(parsed.Include) == 0 {
+		parsed.IncludeDefault = true
+	}
+	return parsed
+}
+
+// relativeToPackage converts a repo-relative pattern into one relative to pkgDir
+func relativeToPackage(pkgDir string, pattern string) string {
+	pattern = path.Clean(pattern)
+	if pkgDir == "." {
+		return pattern
+	}
+	if pattern == pkgDir {
+		return "."
+	}
+	if strings.HasPrefix(pattern, pkgDir+"/") {
+		return strings.TrimPrefix(pattern, pkgDir+"/")
+	}
+	return strings.Repeat("../", strings.Count(pkgDir, "/")+1) + pattern
+}
+
+// IsEmpty returns true if there are no patterns, and so every file in the package is an input
+func (ip InputPatterns) IsEmpty() bool {
+	return ip.IncludeDefault && len(ip.Include) == 0 && len(ip.Exclude) == 0
+}
+
+// Matches returns true if the file at the given package-relative unix path is an input
+func (ip InputPatterns) Matches(file string) (bool, error) {
+	included := ip.IncludeDefault && !strings.HasPrefix(file, "../")
+	// Compare repo-relative paths, so that a pattern reaching outside of the
+	// package can still match files inside of it
+	repoFile := path.Join(ip.pkgDir, file)
+	for _, pattern := range ip.Include {
+		if included {
+			break
+		}
+		match, err := doublestar.Match(path.Join(ip.pkgDir, pattern), repoFile)
+		if err != nil {
+			return false, err
+		}
+		included = match
+	}
+	if !included {
+		return false, nil
+	}
+	for _, pattern := range ip.Exclude {
+		match, err := doublestar.Match(path.Join(ip.pkgDir, pattern), repoFile)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SearchPaths returns the package-relative unix paths that must be searched to find
+// every file that could match. "." is the package directory.
+func (ip InputPatterns) SearchPaths() []string {
+	searchPackage := ip.IncludeDefault
+	var paths []string
+	for _, pattern := range ip.Include {
+		if !strings.HasPrefix(pattern, "../") {
+			searchPackage = true
+			continue
+		}
+		paths = append(paths, globBase(pattern))
+	}
+	if searchPackage {
+		paths = append([]string{"."}, paths...)
+	}
+	return paths
+}
+
+// globBase returns the longest leading portion of pattern that contains no glob syntax.
+// If the pattern contains no glob syntax, it is returned unchanged.
+func globBase(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[{\\") {
+			return path.Join(segments[:i]...)
+		}
+	}
+	return pattern
+}
+
+// GitPathspecs returns the patterns in the form of git pathspecs, relative to the package
+func (ip InputPatterns) GitPathspecs() []string {
+	var pathspecs []string
+	if ip.IncludeDefault {
+		pathspecs = append(pathspecs, ".")
+	}
+	pathspecs = append(pathspecs, ip.Include...)
+	for _, pattern := range ip.Exclude {
+		pathspecs = append(pathspecs, ":(exclude)"+pattern)
+	}
+	return pathspecs
+}
@@ -0,0 +1,96 @@
+package fs
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseInputPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkgDir   string
+		patterns []string
+		want     InputPatterns
+	}{
+		{
+			name:   "no inputs",
+			pkgDir: "packages/ui",
+			want:   InputPatterns{IncludeDefault: true, pkgDir: "packages/ui"},
+		},
+		{
+			name:     "package relative",
+			pkgDir:   "packages/ui",
+			patterns: []string{"src/**/*.ts", "./package.json"},
+			want:     InputPatterns{Include: []string{"src/**/*.ts", "package.json"}, pkgDir: "packages/ui"},
+		},
+		{
+			name:     "root relative",
+			pkgDir:   "packages/ui",
+			patterns: []string{"$ROOT/tsconfig.base.json", "$ROOT/packages/ui/src/**"},
+			want:     InputPatterns{Include: []string{"../../tsconfig.base.json", "src/**"}, pkgDir: "packages/ui"},
+		},
+		{
+			name:     "root relative in root package",
+			pkgDir:   ".",
+			patterns: []string{"$ROOT/schemas/**"},
+			want:     InputPatterns{Include: []string{"schemas/**"}, pkgDir: "."},
+		},
+		{
+			name:     "only negations include the defaults",
+			pkgDir:   "packages/ui",
+			patterns: []string{"!**/*.md", "!$ROOT/packages/ui/docs/**"},
+			want:     InputPatterns{IncludeDefault: true, Exclude: []string{"**/*.md", "docs/**"}, pkgDir: "packages/ui"},
+		},
+		{
+			name:     "defaults plus extras",
+			pkgDir:   "packages/ui",
+			patterns: []string{"$TURBO_DEFAULT$", "$ROOT/schemas/**", "!README.md"},
+			want:     InputPatterns{IncludeDefault: true, Include: []string{"../../schemas/**"}, Exclude: []string{"README.md"}, pkgDir: "packages/ui"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseInputPatterns(tt.pkgDir, tt.patterns)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInputPatterns() got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputPatternsMatches(t *testing.T) {
+	patterns := ParseInputPatterns("packages/ui", []string{"$TURBO_DEFAULT$", "$ROOT/schemas/**", "$ROOT/**/shared.json", "!**/*.md"})
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"src/index.ts", true},
+		{"README.md", false},
+		{"../../schemas/user.json", true},
+		// exclusions are anchored at the package too
+		{"../../schemas/README.md", true},
+		{"../../tsconfig.json", false},
+		{"../web/shared.json", true},
+		{"../web/index.ts", false},
+	}
+	for _, tt := range tests {
+		got, err := patterns.Matches(tt.file)
+		assert.NilError(t, err, tt.file)
+		assert.Equal(t, got, tt.want, tt.file)
+	}
+}
+
+func TestInputPatternsSearchPaths(t *testing.T) {
+	patterns := ParseInputPatterns("packages/ui", []string{"$ROOT/tsconfig.base.json", "$ROOT/schemas/**/*.json"})
+	assert.DeepEqual(t, patterns.SearchPaths(), []string{"../../tsconfig.base.json", "../../schemas"})
+
+	patterns = ParseInputPatterns("packages/ui", []string{"src/**", "$ROOT/schemas/**"})
+	assert.DeepEqual(t, patterns.SearchPaths(), []string{".", "../../schemas"})
+}
+
+func TestInputPatternsGitPathspecs(t *testing.T) {
+	patterns := ParseInputPatterns("packages/ui", []string{"$TURBO_DEFAULT$", "$ROOT/schemas/**", "!README.md"})
+	assert.DeepEqual(t, patterns.GitPathspecs(), []string{".", "../../schemas/**", ":(exclude)README.md"})
+}
@@ -20,22 +20,26 @@ type PackageDepsOptions struct {
 	// containing package.json. If omitted, the default value is the current working directory.
 	PackagePath string
 
+	// InputPatterns are the inputs of a task, as specified in turbo.json. See ParseInputPatterns.
 	InputPatterns []string
 }
 
 // GetPackageDeps Builds an object containing git hashes for the files under the specified `packagePath` folder.
 func GetPackageDeps(rootPath AbsolutePath, p *PackageDepsOptions) (map[turbopath.AnchoredUnixPath]string, error) {
 	pkgPath := rootPath.Join(p.PackagePath)
+	inputs := ParseInputPatterns(p.PackagePath, p.InputPatterns)
+	var pathspecs []string
 	// Add all the checked in hashes.
 	var result map[turbopath.AnchoredUnixPath]string
-	if len(p.InputPatterns) == 0 {
+	if inputs.IsEmpty() {
 		gitLsTreeOutput, err := gitLsTree(pkgPath)
 		if err != nil {
 			return nil, fmt.Errorf("could not get git hashes for files in package %s: %w", p.PackagePath, err)
 		}
 		result = gitLsTreeOutput
 	} else {
-		gitLsFilesOutput, err := gitLsFiles(pkgPath, p.InputPatterns)
+		pathspecs = inputs.GitPathspecs()
+		gitLsFilesOutput, err := gitLsFiles(pkgPath, pathspecs)
 		if err != nil {
 			return nil, fmt.Errorf("could not get git hashes for file patterns %v in package %s: %w", p.InputPatterns, p.PackagePath, err)
 		}
@@ -44,7 +48,7 @@ func GetPackageDeps(rootPath AbsolutePath, p *PackageDepsOptions) (map[turbopath
 
 	// Update the checked in hashes with the current repo status
 	// The paths returned from this call are anchored at the package directory
-	gitStatusOutput, err := gitStatus(pkgPath, p.InputPatterns)
+	gitStatusOutput, err := gitStatus(pkgPath, pathspecs)
 	if err != nil {
 		return nil, fmt.Errorf("Could not get git hashes from git status: %v", err)
 	}
@@ -229,6 +229,7 @@ func requireGitCmd(t *testing.T, repoRoot AbsolutePath, args ...string) {
 func TestGetPackageDeps(t *testing.T) {
 	// Directory structure:
 	// <root>/
+	//   root-file
 	//   my-pkg/
 	//     committed-file
 	//     deleted-file
@@ -244,6 +245,8 @@ func TestGetPackageDeps(t *testing.T) {
 	deletedFilePath := myPkgDir.Join("deleted-file")
 	err = deletedFilePath.WriteFile([]byte("delete-me"), 0644)
 	assert.NilError(t, err, "WriteFile")
+	err = repoRoot.Join("root-file").WriteFile([]byte("root bytes"), 0644)
+	assert.NilError(t, err, "WriteFile")
 	requireGitCmd(t, repoRoot, "init", ".")
 	requireGitCmd(t, repoRoot, "config", "--local", "user.name", "test")
 	requireGitCmd(t, repoRoot, "config", "--local", "user.email", "test@example.com")
@@ -277,6 +280,26 @@ func TestGetPackageDeps(t *testing.T) {
 				"uncommitted-file": "4e56ad89387e6379e4e91ddfe9872cf6a72c9976",
 			},
 		},
+		{
+			opts: &PackageDepsOptions{
+				PackagePath:   "my-pkg",
+				InputPatterns: []string{"!uncommitted-file"},
+			},
+			expected: map[turbopath.AnchoredUnixPath]string{
+				"committed-file": "3a29e62ea9ba15c4a4009d1f605d391cdd262033",
+			},
+		},
+		{
+			opts: &PackageDepsOptions{
+				PackagePath:   "my-pkg",
+				InputPatterns: []string{"$TURBO_DEFAULT$", "$ROOT/root-file"},
+			},
+			expected: map[turbopath.AnchoredUnixPath]string{
+				"committed-file":   "3a29e62ea9ba15c4a4009d1f605d391cdd262033",
+				"uncommitted-file": "4e56ad89387e6379e4e91ddfe9872cf6a72c9976",
+				"../root-file":     "9c3db43c755411c934ed9bf91d40238dd9ad3cd8",
+			},
+		},
 	}
 	for _, tt := range tests {
 		got, err := GetPackageDeps(repoRoot, tt.opts)
@@ -15,6 +15,7 @@ type PackageJSON struct {
 	DevDependencies        map[string]string `json:"devDependencies,omitempty"`
 	OptionalDependencies   map[string]string `json:"optionalDependencies,omitempty"`
 	PeerDependencies       map[string]string `json:"peerDependencies,omitempty"`
+	Resolutions            map[string]string `json:"resolutions,omitempty"`
 	PackageManager         string            `json:"packageManager,omitempty"`
 	Os                     []string          `json:"os,omitempty"`
 	Workspaces             Workspaces        `json:"workspaces,omitempty"`
@@ -25,6 +26,7 @@ type PackageJSON struct {
 	UnresolvedExternalDeps map[string]string
 	ExternalDeps           []string
 	SubLockfile            YarnLockfile
+	SubBerryLockfile       map[string]*BerryLockfileEntry
 	LegacyTurboConfig      *TurboJSON `json:"turbo"`
 	Mu                     sync.Mutex
 	ExternalDepsHash       string
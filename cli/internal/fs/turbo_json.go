@@ -4,7 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/vercel/turborepo/cli/internal/util"
 	"github.com/yosuke-furukawa/json5/encoding/json5"
@@ -21,6 +26,22 @@ type TurboJSON struct {
 	Pipeline Pipeline
 	// Configuration options when interfacing with the remote cache
 	RemoteCacheOptions RemoteCacheOptions `json:"remoteCache,omitempty"`
+	// Whether to infer frameworks for packages in order to hash their public env vars.
+	// Defaults to true when omitted.
+	FrameworkInference *bool `json:"frameworkInference,omitempty"`
+	// Additional frameworks to infer, checked before the builtin frameworks
+	Frameworks []FrameworkDefinition `json:"frameworks,omitempty"`
+}
+
+// FrameworkDefinition is a user-defined framework in turbo.json. Packages which match its
+// dependencies have any env vars beginning with EnvPrefix included in their task hashes.
+type FrameworkDefinition struct {
+	Slug      string `json:"slug"`
+	EnvPrefix string `json:"envPrefix"`
+	// Dependencies are package names, all of which must be dependencies of a package
+	// for it to match, unless Match is "some"
+	Dependencies []string `json:"dependencies"`
+	Match        string   `json:"match,omitempty"`
 }
 
 // ReadTurboConfig toggles between reading from package.json or turbo.json to support early adopters.
@@ -82,11 +103,108 @@ type pipelineJSON struct {
 	DependsOn  []string            `json:"dependsOn,omitempty"`
 	Inputs     []string            `json:"inputs,omitempty"`
 	OutputMode util.TaskOutputMode `json:"outputMode,omitempty"`
+
+	FrameworkInference *bool          `json:"frameworkInference,omitempty"`
+	DotEnv             []string       `json:"dotEnv,omitempty"`
+	Persistent         bool           `json:"persistent,omitempty"`
+	Resources          *TaskResources `json:"resources,omitempty"`
+	Retries            int            `json:"retries,omitempty"`
+	RetryDelay         string         `json:"retryDelay,omitempty"`
+	Timeout            string         `json:"timeout,omitempty"`
+	Matrix             []TaskVariant  `json:"matrix,omitempty"`
+	Args               []string       `json:"args,omitempty"`
+}
+
+// matrixVariantDelimiter separates the name of a task from the name of one of its variants
+const matrixVariantDelimiter = "@"
+
+// _validVariantName matches the names which variants may have
+var _validVariantName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// TaskVariant is one entry in the matrix of a task. Each variant runs the task's
+// script as a separate task, named <task>@<variant>, with its own environment and arguments.
+type TaskVariant struct {
+	Name string `json:"name"`
+	// Env is set in the environment of the variant's command, and included in its hash
+	Env map[string]string `json:"env,omitempty"`
+	// Args are passed to the variant's script
+	Args []string `json:"args,omitempty"`
+	// Outputs replace the outputs of the task for this variant, if set
+	Outputs []string `json:"outputs,omitempty"`
+	// Task is the name of the task which declares the matrix, and the script that the variant runs
+	Task string `json:"-"`
+}
+
+// TaskName returns the name of the task that the variant expands into
+func (v TaskVariant) TaskName() string {
+	return v.Task + matrixVariantDelimiter + v.Name
+}
+
+// EnvPairs returns the variant's environment as sorted KEY=value pairs
+func (v TaskVariant) EnvPairs() []string {
+	pairs := make([]string, 0, len(v.Env))
+	for key, value := range v.Env {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// TaskResources are the share of the machine that a task uses, which is counted
+// against the budget set by --concurrency and --memory-budget
+type TaskResources struct {
+	// CPU is the number of concurrency slots the task occupies. Defaults to 1.
+	CPU int `json:"cpu,omitempty"`
+	// MemoryMB is the amount of memory, in megabytes, the task is expected to use
+	MemoryMB int `json:"memoryMB,omitempty"`
 }
 
 // Pipeline is a struct for deserializing .pipeline in turbo.json
 type Pipeline map[string]TaskDefinition
 
+// UnmarshalJSON deserializes the pipeline, adding an entry for each variant of
+// the tasks which declare a matrix
+func (pc *Pipeline) UnmarshalJSON(data []byte) error {
+	raw := make(map[string]TaskDefinition)
+	// turbo.json may contain comments between pipeline entries
+	if err := json5.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	pipeline := make(Pipeline, len(raw))
+	for taskID, taskDefinition := range raw {
+		pipeline[taskID] = taskDefinition
+	}
+	for taskID, taskDefinition := range raw {
+		if len(taskDefinition.Matrix) == 0 {
+			continue
+		}
+		task := taskID
+		if util.IsPackageTask(taskID) {
+			_, task = util.GetPackageTaskFromId(taskID)
+		}
+		matrix := make([]TaskVariant, len(taskDefinition.Matrix))
+		for i, variant := range taskDefinition.Matrix {
+			variant.Task = task
+			matrix[i] = variant
+			variantID := taskID + matrixVariantDelimiter + variant.Name
+			if _, ok := raw[variantID]; ok {
+				return fmt.Errorf("%v: the variant %q conflicts with the pipeline entry %v", taskID, variant.Name, variantID)
+			}
+			variantDefinition := taskDefinition
+			variantDefinition.Matrix = nil
+			variantDefinition.Variant = variant
+			if variant.Outputs != nil {
+				variantDefinition.Outputs = variant.Outputs
+			}
+			pipeline[variantID] = variantDefinition
+		}
+		taskDefinition.Matrix = matrix
+		pipeline[taskID] = taskDefinition
+	}
+	*pc = pipeline
+	return nil
+}
+
 // GetTaskDefinition returns a TaskDefinition from a serialized definition in turbo.json
 func (pc Pipeline) GetTaskDefinition(taskID string) (TaskDefinition, bool) {
 	if entry, ok := pc[taskID]; ok {
@@ -123,6 +241,34 @@ type TaskDefinition struct {
 	TaskDependencies        []string
 	Inputs                  []string
 	OutputMode              util.TaskOutputMode
+	FrameworkInference      bool
+	// DotEnv lists .env files, relative to the package, which are hashed and
+	// loaded into the task's environment. Later files take precedence.
+	DotEnv []string
+	// Persistent tasks are long-running processes, such as dev servers, which never exit.
+	// They are never cached, and no other task may depend on them.
+	Persistent bool
+	// Resources are used to limit how many tasks run at once. Unset values are zero.
+	Resources TaskResources
+	// Retries is the number of times to re-run the task's command if it fails
+	Retries int
+	// RetryDelay is how long to wait before each retry
+	RetryDelay time.Duration
+	// Timeout is how long the task's command may run before it is stopped. 0 is unlimited.
+	Timeout time.Duration
+	// Matrix lists the variants of the task. Running or depending on the task
+	// runs each of its variants instead.
+	Matrix []TaskVariant
+	// Args are passed to the task's script, unless they are replaced with --args
+	Args []string
+	// Variant is set on the pipeline entries that a matrix expands into, and is
+	// empty otherwise. It isn't a pointer so that hashing the pipeline is deterministic.
+	Variant TaskVariant
+}
+
+// IsVariant returns true if the task definition is one of the entries that a matrix expands into
+func (c TaskDefinition) IsVariant() bool {
+	return c.Variant.Name != ""
 }
 
 const (
@@ -167,5 +313,98 @@ func (c *TaskDefinition) UnmarshalJSON(data []byte) error {
 	}
 	c.Inputs = rawPipeline.Inputs
 	c.OutputMode = rawPipeline.OutputMode
+	if rawPipeline.FrameworkInference == nil {
+		c.FrameworkInference = true
+	} else {
+		c.FrameworkInference = *rawPipeline.FrameworkInference
+	}
+	c.DotEnv = rawPipeline.DotEnv
+	c.Persistent = rawPipeline.Persistent
+	if c.Persistent {
+		// A persistent task never completes, so there is never anything to cache
+		c.ShouldCache = false
+	}
+	if rawPipeline.Resources != nil {
+		if rawPipeline.Resources.CPU < 0 || rawPipeline.Resources.MemoryMB < 0 {
+			return fmt.Errorf("task resources cannot be negative: %+v", *rawPipeline.Resources)
+		}
+		c.Resources = *rawPipeline.Resources
+	}
+	if rawPipeline.Retries < 0 {
+		return fmt.Errorf("task retries cannot be negative: %v", rawPipeline.Retries)
+	}
+	c.Retries = rawPipeline.Retries
+	if rawPipeline.RetryDelay != "" {
+		retryDelay, err := time.ParseDuration(rawPipeline.RetryDelay)
+		if err != nil {
+			return fmt.Errorf("invalid retryDelay %q, expected a duration such as \"10s\": %w", rawPipeline.RetryDelay, err)
+		}
+		c.RetryDelay = retryDelay
+	}
+	if rawPipeline.Timeout != "" {
+		timeout, err := time.ParseDuration(rawPipeline.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q, expected a duration such as \"10m\": %w", rawPipeline.Timeout, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("task timeout must be positive: %v", rawPipeline.Timeout)
+		}
+		c.Timeout = timeout
+	}
+	names := make(util.Set)
+	for _, variant := range rawPipeline.Matrix {
+		if !_validVariantName.MatchString(variant.Name) {
+			return fmt.Errorf("invalid matrix variant name %q, expected letters, digits, \"-\", \"_\" or \".\"", variant.Name)
+		}
+		if names.Includes(variant.Name) {
+			return fmt.Errorf("duplicate matrix variant name %q", variant.Name)
+		}
+		names.Add(variant.Name)
+	}
+	if c.ShouldCache {
+		if err := checkVariantOutputs(c.Outputs, rawPipeline.Matrix); err != nil {
+			return err
+		}
+	}
+	c.Matrix = rawPipeline.Matrix
+	c.Args = rawPipeline.Args
+	return nil
+}
+
+// checkVariantOutputs returns an error if the outputs of two variants of a task may
+// include the same files. Variants can run at the same time, and each one's outputs
+// are cached and restored separately, so they would overwrite each other.
+func checkVariantOutputs(taskOutputs []string, matrix []TaskVariant) error {
+	outputs := func(variant TaskVariant) []string {
+		if variant.Outputs != nil {
+			return variant.Outputs
+		}
+		return taskOutputs
+	}
+	for i, a := range matrix {
+		for _, b := range matrix[i+1:] {
+			for _, globA := range outputs(a) {
+				for _, globB := range outputs(b) {
+					if outputGlobsOverlap(globA, globB) {
+						return fmt.Errorf("matrix variants %q and %q have overlapping outputs %q and %q, set outputs in separate directories for each variant", a.Name, b.Name, globA, globB)
+					}
+				}
+			}
+		}
+	}
 	return nil
 }
+
+// outputGlobsOverlap returns whether two output globs may match the same files. Globs
+// are only known not to overlap when they are rooted in separate directories, or name
+// separate files.
+func outputGlobsOverlap(a string, b string) bool {
+	if strings.HasPrefix(a, "!") || strings.HasPrefix(b, "!") {
+		return false
+	}
+	baseA, baseB := globBase(path.Clean(filepath.ToSlash(a))), globBase(path.Clean(filepath.ToSlash(b)))
+	if baseA == "" || baseB == "" || baseA == baseB {
+		return true
+	}
+	return strings.HasPrefix(baseA, baseB+"/") || strings.HasPrefix(baseB, baseA+"/")
+}
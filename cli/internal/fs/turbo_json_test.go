@@ -1,9 +1,11 @@
 package fs
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vercel/turborepo/cli/internal/util"
@@ -24,6 +26,8 @@ func Test_ReadTurboJSON(t *testing.T) {
 		t.Fatalf("invalid parse: %#v", err)
 	}
 
+	nodeVariant := TaskVariant{Name: "node", Env: map[string]string{"TARGET": "node"}, Outputs: []string{"dist/node/**"}, Task: "bundle"}
+	browserVariant := TaskVariant{Name: "browser", Env: map[string]string{"TARGET": "browser"}, Args: []string{"--minify"}, Outputs: []string{"dist/browser/**"}, Task: "bundle"}
 	pipelineExpected := map[string]TaskDefinition{
 		"build": {
 			Outputs:                 []string{"dist/**", ".next/**"},
@@ -32,6 +36,9 @@ func Test_ReadTurboJSON(t *testing.T) {
 			TaskDependencies:        []string{},
 			ShouldCache:             true,
 			OutputMode:              util.NewTaskOutput,
+			FrameworkInference:      true,
+			DotEnv:                  []string{".env", ".env.local"},
+			Resources:               TaskResources{CPU: 4, MemoryMB: 4000},
 		},
 		"lint": {
 			Outputs:                 []string{},
@@ -40,6 +47,11 @@ func Test_ReadTurboJSON(t *testing.T) {
 			TaskDependencies:        []string{},
 			ShouldCache:             true,
 			OutputMode:              util.NewTaskOutput,
+			FrameworkInference:      true,
+			Retries:                 2,
+			RetryDelay:              5 * time.Second,
+			Timeout:                 10 * time.Minute,
+			Args:                    []string{"--max-warnings", "0"},
 		},
 		"dev": {
 			Outputs:                 defaultOutputs,
@@ -48,6 +60,35 @@ func Test_ReadTurboJSON(t *testing.T) {
 			TaskDependencies:        []string{},
 			ShouldCache:             false,
 			OutputMode:              util.FullTaskOutput,
+			FrameworkInference:      true,
+			Persistent:              true,
+		},
+		"bundle": {
+			Outputs:                 []string{"dist/**"},
+			EnvVarDependencies:      []string{},
+			TopologicalDependencies: []string{},
+			TaskDependencies:        []string{},
+			ShouldCache:             true,
+			FrameworkInference:      true,
+			Matrix:                  []TaskVariant{nodeVariant, browserVariant},
+		},
+		"bundle@node": {
+			Outputs:                 []string{"dist/node/**"},
+			EnvVarDependencies:      []string{},
+			TopologicalDependencies: []string{},
+			TaskDependencies:        []string{},
+			ShouldCache:             true,
+			FrameworkInference:      true,
+			Variant:                 nodeVariant,
+		},
+		"bundle@browser": {
+			Outputs:                 []string{"dist/browser/**"},
+			EnvVarDependencies:      []string{},
+			TopologicalDependencies: []string{},
+			TaskDependencies:        []string{},
+			ShouldCache:             true,
+			FrameworkInference:      true,
+			Variant:                 browserVariant,
 		},
 		"publish": {
 			Outputs:                 []string{"dist/**"},
@@ -57,6 +98,7 @@ func Test_ReadTurboJSON(t *testing.T) {
 			ShouldCache:             false,
 			Inputs:                  []string{"build/**/*"},
 			OutputMode:              util.FullTaskOutput,
+			FrameworkInference:      true,
 		},
 	}
 
@@ -81,3 +123,43 @@ func Test_ReadTurboJSON(t *testing.T) {
 	}
 	assert.EqualValues(t, remoteCacheOptionsExpected, turboJSON.RemoteCacheOptions)
 }
+
+func Test_PipelineMatrix(t *testing.T) {
+	var pipeline Pipeline
+	err := json.Unmarshal([]byte(`{"web#build": {"matrix": [{"name": "edge", "args": ["--edge"]}]}}`), &pipeline)
+	assert.NoError(t, err)
+	assert.Len(t, pipeline, 2)
+	variant := pipeline["web#build@edge"].Variant
+	assert.Equal(t, "build", variant.Task)
+	assert.Equal(t, "build@edge", variant.TaskName())
+	assert.Equal(t, []string{"--edge"}, variant.Args)
+	assert.Nil(t, pipeline["web#build@edge"].Matrix)
+	assert.Equal(t, "build", pipeline["web#build"].Matrix[0].Task)
+
+	invalid := map[string]string{
+		"duplicate name": `{"build": {"matrix": [{"name": "node"}, {"name": "node"}]}}`,
+		"invalid name":   `{"build": {"matrix": [{"name": "node#18"}]}}`,
+		"missing name":   `{"build": {"matrix": [{"env": {"TARGET": "node"}}]}}`,
+		"conflict":       `{"build": {"matrix": [{"name": "node"}]}, "build@node": {}}`,
+		"shared outputs": `{"build": {"outputs": ["dist/**"], "matrix": [{"name": "node"}, {"name": "web"}]}}`,
+		"nested outputs": `{"build": {"matrix": [{"name": "node", "outputs": ["dist/**"]}, {"name": "web", "outputs": ["dist/web/**"]}]}}`,
+	}
+	for name, contents := range invalid {
+		var pipeline Pipeline
+		assert.Error(t, json.Unmarshal([]byte(contents), &pipeline), name)
+	}
+
+	valid := map[string]string{
+		"separate outputs": `{"build": {"matrix": [{"name": "node", "outputs": ["dist/node/**"]}, {"name": "web", "outputs": ["dist/web/**", "!dist/**/*.map"]}]}}`,
+		"uncached":         `{"build": {"cache": false, "matrix": [{"name": "node"}, {"name": "web"}]}}`,
+	}
+	for name, contents := range valid {
+		var pipeline Pipeline
+		assert.NoError(t, json.Unmarshal([]byte(contents), &pipeline), name)
+	}
+}
+
+func Test_TaskVariantEnvPairs(t *testing.T) {
+	variant := TaskVariant{Env: map[string]string{"TARGET": "edge", "MINIFY": "true"}}
+	assert.Equal(t, []string{"MINIFY=true", "TARGET=edge"}, variant.EnvPairs())
+}
@@ -1,24 +1,62 @@
 package graphvisualizer
 
 import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/mitchellh/cli"
 	"github.com/pyr-sh/dag"
 	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/core"
 	"github.com/vercel/turborepo/cli/internal/ui"
 	"github.com/vercel/turborepo/cli/internal/util/browser"
 )
 
+//go:embed viewer.html
+var viewerHTML string
+
 // GraphVisualizer requirements
 type GraphVisualizer struct {
 	config    *config.Config
 	ui        cli.Ui
+	kind      string
 	TaskGraph *dag.AcyclicGraph
+	describe  Describer
+}
+
+// Node is a vertex of a rendered graph, along with what turbo knows about it
+type Node struct {
+	ID         string   `json:"id"`
+	Package    string   `json:"package"`
+	Task       string   `json:"task,omitempty"`
+	Directory  string   `json:"directory,omitempty"`
+	Command    string   `json:"command,omitempty"`
+	Outputs    []string `json:"outputs,omitempty"`
+	Cache      *bool    `json:"cache,omitempty"`
+	Persistent bool     `json:"persistent,omitempty"`
+}
+
+// Edge connects a vertex to one of its dependencies
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Describer returns the metadata for the vertex with the given id
+type Describer func(id string) Node
+
+// graphJSON is the document written for .json graph files
+type graphJSON struct {
+	Type  string `json:"type"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
 }
 
 // hasGraphViz checks for the presence of https://graphviz.org/
@@ -27,12 +65,15 @@ func hasGraphViz() bool {
 	return err == nil
 }
 
-// New creates an instance of ColorCache with helpers for adding colors to task outputs
-func New(config *config.Config, ui cli.Ui, TaskGraph *dag.AcyclicGraph) *GraphVisualizer {
+// New creates a GraphVisualizer for a graph of the given kind ("task" or "package"),
+// using describe to look up the metadata for each of its vertices
+func New(config *config.Config, ui cli.Ui, kind string, graph *dag.AcyclicGraph, describe Describer) *GraphVisualizer {
 	return &GraphVisualizer{
 		config:    config,
 		ui:        ui,
-		TaskGraph: TaskGraph,
+		kind:      kind,
+		TaskGraph: graph,
+		describe:  describe,
 	}
 }
 
@@ -44,9 +85,94 @@ func (g *GraphVisualizer) generateDotString() string {
 	}))
 }
 
+// nodesAndEdges lists the vertices and edges of the graph in a stable order,
+// leaving out the placeholder root node
+func (g *GraphVisualizer) nodesAndEdges() ([]Node, []Edge) {
+	nodes := []Node{}
+	for _, v := range g.TaskGraph.Vertices() {
+		id := dag.VertexName(v)
+		if strings.Contains(id, core.ROOT_NODE_NAME) {
+			continue
+		}
+		nodes = append(nodes, g.describe(id))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	edges := []Edge{}
+	for _, e := range g.TaskGraph.Edges() {
+		from := dag.VertexName(e.Source())
+		to := dag.VertexName(e.Target())
+		if strings.Contains(from, core.ROOT_NODE_NAME) || strings.Contains(to, core.ROOT_NODE_NAME) {
+			continue
+		}
+		edges = append(edges, Edge{From: from, To: to})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return nodes, edges
+}
+
+// document collects the graph into the form written to .json and .html files
+func (g *GraphVisualizer) document() *graphJSON {
+	nodes, edges := g.nodesAndEdges()
+	return &graphJSON{
+		Type:  g.kind,
+		Nodes: nodes,
+		Edges: edges,
+	}
+}
+
+// generateJSON renders the graph as a list of nodes and edges
+func (g *GraphVisualizer) generateJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// Commands commonly contain &, < and >, which don't need escaping outside of HTML
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(g.document()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// generateMermaidString renders the graph as a Mermaid flowchart
+func (g *GraphVisualizer) generateMermaidString() string {
+	nodes, edges := g.nodesAndEdges()
+	ids := make(map[string]string, len(nodes))
+	var sb strings.Builder
+	sb.WriteString("graph TD\n")
+	for i, node := range nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		// Mermaid labels can't contain a literal double quote
+		label := strings.ReplaceAll(node.ID, `"`, "#quot;")
+		sb.WriteString(fmt.Sprintf("\t%s[\"%s\"]\n", ids[node.ID], label))
+	}
+	for _, edge := range edges {
+		sb.WriteString(fmt.Sprintf("\t%s --> %s\n", ids[edge.From], ids[edge.To]))
+	}
+	return sb.String()
+}
+
+// generateHTML renders a standalone page for exploring the graph in a browser
+func (g *GraphVisualizer) generateHTML() ([]byte, error) {
+	data, err := json.Marshal(g.document())
+	if err != nil {
+		return nil, err
+	}
+	// json.Marshal escapes <, > and &, so the data can't close the script tag it is embedded in
+	page := strings.ReplaceAll(viewerHTML, "__TURBO_GRAPH_TITLE__", fmt.Sprintf("Turborepo %s graph", g.kind))
+	page = strings.Replace(page, "__TURBO_GRAPH_DATA__", string(data), 1)
+	return []byte(page), nil
+}
+
 // Outputs a warning when a file was requested, but graphviz is not available
 func (g *GraphVisualizer) graphVizWarnUI() {
-	g.ui.Warn(color.New(color.FgYellow, color.Bold, color.ReverseVideo).Sprint(" WARNING ") + color.YellowString(" `turbo` uses Graphviz to generate an image of your\ngraph, but Graphviz isn't installed on this machine.\n\nYou can download Graphviz from https://graphviz.org/download.\n\nIn the meantime, you can use this string output with an\nonline Dot graph viewer."))
+	g.ui.Warn(color.New(color.FgYellow, color.Bold, color.ReverseVideo).Sprint(" WARNING ") + color.YellowString(" `turbo` uses Graphviz to generate an image of your\ngraph, but Graphviz isn't installed on this machine.\n\nYou can download Graphviz from https://graphviz.org/download.\n\nIn the meantime, you can use this string output with an\nonline Dot graph viewer, or write the graph to a .html,\n.json or .mmd file, which doesn't need Graphviz."))
 }
 
 // RenderDotGraph renders a dot graph string for the current TaskGraph
@@ -57,7 +183,6 @@ func (g *GraphVisualizer) RenderDotGraph() {
 
 // GenerateGraphFile saves a visualization of the TaskGraph to a file (or renders a DotGraph as a fallback))
 func (g *GraphVisualizer) GenerateGraphFile(outputName string) error {
-	graphString := g.generateDotString()
 	outputFilename := g.config.Cwd.Join(outputName)
 	ext := outputFilename.Ext()
 	// use .jpg as default extension if none is provided
@@ -65,42 +190,32 @@ func (g *GraphVisualizer) GenerateGraphFile(outputName string) error {
 		ext = ".jpg"
 		outputFilename = g.config.Cwd.Join(outputName + ext)
 	}
-	if ext == ".html" {
-		f, err := outputFilename.Create()
+	var contents []byte
+	switch ext {
+	case ".json":
+		data, err := g.generateJSON()
 		if err != nil {
-			return fmt.Errorf("error creating file: %w", err)
+			return fmt.Errorf("error rendering graph: %w", err)
 		}
-		defer f.Close() //nolint errcheck
-		_, writeErr1 := f.WriteString(`<!DOCTYPE html>
-    <html>
-    <head>
-      <meta charset="utf-8">
-      <title>Graph</title>
-    </head>
-    <body>
-      <script src="https://cdn.jsdelivr.net/npm/viz.js@2.1.2-pre.1/viz.js"></script>
-      <script src="https://cdn.jsdelivr.net/npm/viz.js@2.1.2-pre.1/full.render.js"></script>
-      <script>`)
-		if writeErr1 != nil {
-			return fmt.Errorf("error writing graph contents: %w", writeErr1)
-		}
-
-		_, writeErr2 := f.WriteString("const s = `" + graphString + "`.replace(/\\_\\_\\_ROOT\\_\\_\\_/g, \"Root\").replace(/\\[root\\]/g, \"\");new Viz().renderSVGElement(s).then(el => document.body.appendChild(el)).catch(e => console.error(e));")
-		if writeErr2 != nil {
-			return fmt.Errorf("error creating file: %w", writeErr2)
+		contents = data
+	case ".mmd", ".mermaid":
+		contents = []byte(g.generateMermaidString())
+	case ".dot", ".gv":
+		contents = []byte(g.generateDotString())
+	case ".html":
+		data, err := g.generateHTML()
+		if err != nil {
+			return fmt.Errorf("error rendering graph: %w", err)
 		}
-
-		_, writeErr3 := f.WriteString(`
-    </script>
-  </body>
-  </html>`)
-		if writeErr3 != nil {
-			return fmt.Errorf("error creating file: %w", writeErr3)
+		contents = data
+	}
+	if contents != nil {
+		if err := outputFilename.WriteFile(contents, 0644); err != nil {
+			return fmt.Errorf("error writing graph contents: %w", err)
 		}
-
 		g.ui.Output("")
-		g.ui.Output(fmt.Sprintf("✔ Generated task graph in %s", ui.Bold(outputFilename.ToString())))
-		if ui.IsTTY {
+		g.ui.Output(fmt.Sprintf("✔ Generated %s graph in %s", g.kind, ui.Bold(outputFilename.ToString())))
+		if ext == ".html" && ui.IsTTY {
 			if err := browser.OpenBrowser(outputFilename.ToString()); err != nil {
 				g.ui.Warn(color.New(color.FgYellow, color.Bold, color.ReverseVideo).Sprintf("failed to open browser. Please navigate to file://%v", filepath.ToSlash(outputFilename.ToString())))
 			}
@@ -111,12 +226,12 @@ func (g *GraphVisualizer) GenerateGraphFile(outputName string) error {
 	if hasDot {
 		dotArgs := []string{"-T" + ext[1:], "-o", outputFilename.ToString()}
 		cmd := exec.Command("dot", dotArgs...)
-		cmd.Stdin = strings.NewReader(graphString)
+		cmd.Stdin = strings.NewReader(g.generateDotString())
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("could not generate task graphfile %v:  %w", outputFilename, err)
+			return fmt.Errorf("could not generate %s graphfile %v:  %w", g.kind, outputFilename, err)
 		}
 		g.ui.Output("")
-		g.ui.Output(fmt.Sprintf("✔ Generated task graph in %s", ui.Bold(outputFilename.ToString())))
+		g.ui.Output(fmt.Sprintf("✔ Generated %s graph in %s", g.kind, ui.Bold(outputFilename.ToString())))
 
 	} else {
 		g.ui.Output("")
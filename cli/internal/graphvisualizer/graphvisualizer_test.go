@@ -0,0 +1,79 @@
+package graphvisualizer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/core"
+)
+
+func testVisualizer() *GraphVisualizer {
+	graph := &dag.AcyclicGraph{}
+	graph.Add("web#build")
+	graph.Add("ui#build")
+	graph.Add(core.ROOT_NODE_NAME)
+	graph.Connect(dag.BasicEdge("web#build", "ui#build"))
+	graph.Connect(dag.BasicEdge("ui#build", core.ROOT_NODE_NAME))
+	describe := func(id string) Node {
+		pkg := strings.Split(id, "#")[0]
+		return Node{ID: id, Package: pkg, Task: "build", Command: `echo "` + pkg + `"`}
+	}
+	return New(nil, nil, "task", graph, describe)
+}
+
+func TestGenerateJSON(t *testing.T) {
+	data, err := testVisualizer().generateJSON()
+	if err != nil {
+		t.Fatalf("generateJSON: %v", err)
+	}
+	got := &graphJSON{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("unmarshalling graph: %v", err)
+	}
+	expected := &graphJSON{
+		Type: "task",
+		Nodes: []Node{
+			{ID: "ui#build", Package: "ui", Task: "build", Command: `echo "ui"`},
+			{ID: "web#build", Package: "web", Task: "build", Command: `echo "web"`},
+		},
+		Edges: []Edge{
+			{From: "web#build", To: "ui#build"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("generateJSON got %v, want %v", got, expected)
+	}
+}
+
+func TestGenerateMermaidString(t *testing.T) {
+	got := testVisualizer().generateMermaidString()
+	expected := "graph TD\n\tn0[\"ui#build\"]\n\tn1[\"web#build\"]\n\tn1 --> n0\n"
+	if got != expected {
+		t.Errorf("generateMermaidString got %q, want %q", got, expected)
+	}
+}
+
+func TestGenerateHTML(t *testing.T) {
+	graph := &dag.AcyclicGraph{}
+	graph.Add("</script><b>#build")
+	describe := func(id string) Node {
+		return Node{ID: id}
+	}
+	page, err := New(nil, nil, "task", graph, describe).generateHTML()
+	if err != nil {
+		t.Fatalf("generateHTML: %v", err)
+	}
+	html := string(page)
+	if strings.Contains(html, "__TURBO_GRAPH_DATA__") || strings.Contains(html, "__TURBO_GRAPH_TITLE__") {
+		t.Error("expected the graph data and title to be filled in")
+	}
+	if strings.Contains(html, "</script><b>") {
+		t.Error("expected node names to be escaped inside the page's script")
+	}
+	if strings.Contains(html, "cdn.") || strings.Contains(html, "<script src=") {
+		t.Error("expected the page not to load external scripts")
+	}
+}
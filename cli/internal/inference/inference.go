@@ -1,6 +1,10 @@
 package inference
 
-import "github.com/vercel/turborepo/cli/internal/fs"
+import (
+	"fmt"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
 
 // Framework is an identifier for something that we wish to inference against.
 type Framework struct {
@@ -145,13 +149,60 @@ func (f Framework) match(pkg *fs.PackageJSON) bool {
 	return f.DependencyMatch.match(pkg)
 }
 
+// Frameworks returns the frameworks to infer against, given the configuration in turbo.json.
+// User-defined frameworks are checked before the builtin ones. If inference is disabled,
+// the returned list is empty.
+func Frameworks(turboJSON *fs.TurboJSON) ([]Framework, error) {
+	if turboJSON == nil {
+		return _frameworks, nil
+	}
+	if turboJSON.FrameworkInference != nil && !*turboJSON.FrameworkInference {
+		return []Framework{}, nil
+	}
+	frameworks := make([]Framework, 0, len(turboJSON.Frameworks)+len(_frameworks))
+	for i, definition := range turboJSON.Frameworks {
+		if definition.Slug == "" {
+			return nil, fmt.Errorf("frameworks[%v] is missing a slug", i)
+		}
+		if definition.EnvPrefix == "" {
+			return nil, fmt.Errorf("framework %v is missing an envPrefix", definition.Slug)
+		}
+		if len(definition.Dependencies) == 0 {
+			return nil, fmt.Errorf("framework %v must list at least one dependency", definition.Slug)
+		}
+		var strategy matchStrategy
+		switch definition.Match {
+		case "", "all":
+			strategy = all
+		case "some":
+			strategy = some
+		default:
+			return nil, fmt.Errorf("framework %v has unknown match %q, expected \"all\" or \"some\"", definition.Slug, definition.Match)
+		}
+		frameworks = append(frameworks, Framework{
+			Slug:      definition.Slug,
+			EnvPrefix: definition.EnvPrefix,
+			DependencyMatch: matcher{
+				strategy:     strategy,
+				dependencies: definition.Dependencies,
+			},
+		})
+	}
+	return append(frameworks, _frameworks...), nil
+}
+
 // InferFramework returns a reference to a matched framework
 func InferFramework(pkg *fs.PackageJSON) *Framework {
+	return InferFrameworkFrom(_frameworks, pkg)
+}
+
+// InferFrameworkFrom returns a reference to the first of the given frameworks matching pkg
+func InferFrameworkFrom(frameworks []Framework, pkg *fs.PackageJSON) *Framework {
 	if pkg == nil {
 		return nil
 	}
 
-	for _, candidateFramework := range _frameworks {
+	for _, candidateFramework := range frameworks {
 		if candidateFramework.match(pkg) {
 			return &candidateFramework
 		}
@@ -85,3 +85,86 @@ func TestInferFramework(t *testing.T) {
 		})
 	}
 }
+
+func TestFrameworks(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name      string
+		turboJSON *fs.TurboJSON
+		pkg       *fs.PackageJSON
+		wantSlug  string
+		wantErr   bool
+	}{
+		{
+			name:      "Builtin frameworks by default",
+			turboJSON: &fs.TurboJSON{},
+			pkg:       &fs.PackageJSON{UnresolvedExternalDeps: map[string]string{"next": "*"}},
+			wantSlug:  "nextjs",
+		},
+		{
+			name:      "Inference can be disabled",
+			turboJSON: &fs.TurboJSON{FrameworkInference: &disabled},
+			pkg:       &fs.PackageJSON{UnresolvedExternalDeps: map[string]string{"next": "*"}},
+			wantSlug:  "",
+		},
+		{
+			name: "Custom frameworks take priority",
+			turboJSON: &fs.TurboJSON{Frameworks: []fs.FrameworkDefinition{
+				{Slug: "my-next", EnvPrefix: "MY_NEXT_", Dependencies: []string{"next", "my-plugin"}},
+			}},
+			pkg:      &fs.PackageJSON{UnresolvedExternalDeps: map[string]string{"next": "*", "my-plugin": "*"}},
+			wantSlug: "my-next",
+		},
+		{
+			name: "Custom frameworks fall back to builtins",
+			turboJSON: &fs.TurboJSON{Frameworks: []fs.FrameworkDefinition{
+				{Slug: "my-next", EnvPrefix: "MY_NEXT_", Dependencies: []string{"next", "my-plugin"}},
+			}},
+			pkg:      &fs.PackageJSON{UnresolvedExternalDeps: map[string]string{"next": "*"}},
+			wantSlug: "nextjs",
+		},
+		{
+			name: "Custom frameworks support the some strategy",
+			turboJSON: &fs.TurboJSON{Frameworks: []fs.FrameworkDefinition{
+				{Slug: "qwik", EnvPrefix: "PUBLIC_", Dependencies: []string{"@builder.io/qwik", "@builder.io/qwik-city"}, Match: "some"},
+			}},
+			pkg:      &fs.PackageJSON{UnresolvedExternalDeps: map[string]string{"@builder.io/qwik-city": "*"}},
+			wantSlug: "qwik",
+		},
+		{
+			name: "Missing envPrefix is an error",
+			turboJSON: &fs.TurboJSON{Frameworks: []fs.FrameworkDefinition{
+				{Slug: "qwik", Dependencies: []string{"@builder.io/qwik"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Unknown match strategy is an error",
+			turboJSON: &fs.TurboJSON{Frameworks: []fs.FrameworkDefinition{
+				{Slug: "qwik", EnvPrefix: "PUBLIC_", Dependencies: []string{"@builder.io/qwik"}, Match: "any"},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frameworks, err := Frameworks(tt.turboJSON)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Frameworks() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Frameworks() error = %v", err)
+			}
+			slug := ""
+			if framework := InferFrameworkFrom(frameworks, tt.pkg); framework != nil {
+				slug = framework.Slug
+			}
+			if slug != tt.wantSlug {
+				t.Errorf("InferFrameworkFrom() = %v, want %v", slug, tt.wantSlug)
+			}
+		})
+	}
+}
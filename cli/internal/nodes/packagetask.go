@@ -19,10 +19,19 @@ type PackageTask struct {
 	TaskDefinition *fs.TaskDefinition
 }
 
+// Script returns the name of the package.json script that this task runs. The
+// variants of a task in a matrix run that task's script.
+func (pt *PackageTask) Script() string {
+	if pt.TaskDefinition != nil && pt.TaskDefinition.IsVariant() {
+		return pt.TaskDefinition.Variant.Task
+	}
+	return pt.Task
+}
+
 // Command returns the script for this task from package.json and a boolean indicating
 // whether or not it exists
 func (pt *PackageTask) Command() (string, bool) {
-	cmd, ok := pt.Pkg.Scripts[pt.Task]
+	cmd, ok := pt.Pkg.Scripts[pt.Script()]
 	return cmd, ok
 }
 
@@ -6,7 +6,6 @@ import (
 
 	"github.com/Masterminds/semver"
 	"github.com/vercel/turborepo/cli/internal/fs"
-	"github.com/vercel/turborepo/cli/internal/util"
 )
 
 var nodejsBerry = PackageManager{
@@ -58,7 +57,6 @@ var nodejsBerry = PackageManager{
 	},
 
 	// Detect for berry needs to identify which version of yarn is running on the system.
-	// Further, berry can be configured in an incompatible way, so we check for compatibility here as well.
 	detect: func(projectDirectory fs.AbsolutePath, packageManager *PackageManager) (bool, error) {
 		specfileExists := projectDirectory.Join(packageManager.Specfile).FileExists()
 		lockfileExists := projectDirectory.Join(packageManager.Lockfile).FileExists()
@@ -75,28 +73,14 @@ var nodejsBerry = PackageManager{
 			return false, fmt.Errorf("could not detect yarn version: %w", err)
 		}
 
-		// See if we're a match when we compare these two things.
+		// See if we're a match when we compare these two things. Both the node-modules
+		// and Plug'n'Play linkers are supported, so there's nothing else to check.
 		matches, _ := packageManager.Matches(packageManager.Slug, string(out))
+		return matches, nil
+	},
 
-		// Short-circuit, definitely not Berry because version number says we're Yarn.
-		if !matches {
-			return false, nil
-		}
-
-		// We're Berry!
-
-		// Check for supported configuration.
-		isNMLinker, err := util.IsNMLinker(projectDirectory.ToStringDuringMigration())
-
-		if err != nil {
-			// Failed to read the linker state, so we treat an unknown configuration as a failure.
-			return false, fmt.Errorf("could not check if yarn is using nm-linker: %w", err)
-		} else if !isNMLinker {
-			// Not using nm-linker, so unsupported configuration.
-			return false, fmt.Errorf("only yarn nm-linker is supported")
-		}
-
-		// Berry, supported configuration.
-		return true, nil
+	runsPrePostScripts: func(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool {
+		// yarn 2 dropped support for pre and post scripts
+		return false
 	},
 }
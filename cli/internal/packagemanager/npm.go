@@ -45,4 +45,8 @@ var nodejsNpm = PackageManager{
 
 		return (specfileExists && lockfileExists), nil
 	},
+
+	runsPrePostScripts: func(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool {
+		return true
+	},
 }
@@ -7,6 +7,7 @@ package packagemanager
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -47,6 +48,9 @@ type PackageManager struct {
 
 	// Detect if the project is using the Package Manager by inspecting the system.
 	detect func(projectDirectory fs.AbsolutePath, packageManager *PackageManager) (bool, error)
+
+	// Return whether the Package Manager runs the pre<script> and post<script> scripts around a script
+	runsPrePostScripts func(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool
 }
 
 var packageManagers = []PackageManager{
@@ -144,3 +148,27 @@ func (pm PackageManager) GetWorkspaces(rootpath fs.AbsolutePath) ([]string, erro
 func (pm PackageManager) GetWorkspaceIgnores(rootpath fs.AbsolutePath) ([]string, error) {
 	return pm.getWorkspaceIgnores(pm, rootpath)
 }
+
+// RunsPrePostScripts returns whether running a script with the package manager also
+// runs the package's pre<script> and post<script> scripts, before and after it.
+func (pm PackageManager) RunsPrePostScripts(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool {
+	return pm.runsPrePostScripts(rootpath, rootPackageJSON)
+}
+
+// Version returns the version of the package manager, from the packageManager field
+// of the root package.json if it names this package manager, or else by running it.
+func (pm PackageManager) Version(rootPackageJSON *fs.PackageJSON) (string, error) {
+	return commandVersion(pm.Command, rootPackageJSON)
+}
+
+// commandVersion returns the version of the package manager run by command
+func commandVersion(command string, rootPackageJSON *fs.PackageJSON) (string, error) {
+	if manager, version, err := ParsePackageManagerString(rootPackageJSON.PackageManager); err == nil && manager == command {
+		return version, nil
+	}
+	out, err := exec.Command(command, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get %v version: %w", command, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
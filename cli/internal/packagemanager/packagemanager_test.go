@@ -330,3 +330,17 @@ func Test_GetWorkspaceIgnores(t *testing.T) {
 		})
 	}
 }
+
+func Test_RunsPrePostScripts(t *testing.T) {
+	rootPath := fs.AbsolutePathFromUpstream(t.TempDir())
+	pkg := &fs.PackageJSON{}
+	assert.Assert(t, nodejsNpm.RunsPrePostScripts(rootPath, pkg), "npm")
+	assert.Assert(t, nodejsYarn.RunsPrePostScripts(rootPath, pkg), "yarn")
+	assert.Assert(t, !nodejsBerry.RunsPrePostScripts(rootPath, pkg), "berry")
+	assert.Assert(t, !nodejsPnpm.RunsPrePostScripts(rootPath, pkg), "pnpm")
+	assert.Assert(t, nodejsPnpm.RunsPrePostScripts(rootPath, &fs.PackageJSON{PackageManager: "pnpm@6.32.2"}), "pnpm 6")
+
+	npmrc := "# pnpm settings\nenable-pre-post-scripts = true\n"
+	assert.NilError(t, rootPath.Join(".npmrc").WriteFile([]byte(npmrc), 0644), "WriteFile")
+	assert.Assert(t, nodejsPnpm.RunsPrePostScripts(rootPath, pkg), "pnpm with enable-pre-post-scripts")
+}
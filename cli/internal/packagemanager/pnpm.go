@@ -3,7 +3,9 @@ package packagemanager
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
+	"github.com/Masterminds/semver"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"gopkg.in/yaml.v3"
 )
@@ -60,4 +62,34 @@ var nodejsPnpm = PackageManager{
 
 		return (specfileExists && lockfileExists), nil
 	},
+
+	runsPrePostScripts: func(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool {
+		// pnpm 7 stopped running pre and post scripts, unless enable-pre-post-scripts is set
+		version, _ := commandVersion("pnpm", rootPackageJSON)
+		if v, err := semver.NewVersion(version); err == nil && v.Major() < 7 {
+			return true
+		}
+		return readNpmrcSetting(rootpath, "enable-pre-post-scripts") == "true"
+	},
+}
+
+// readNpmrcSetting returns the value of a setting in the .npmrc file at the root of
+// the repository, or "" if it isn't set
+func readNpmrcSetting(rootpath fs.AbsolutePath, key string) string {
+	contents, err := ioutil.ReadFile(rootpath.Join(".npmrc").ToStringDuringMigration())
+	if err != nil {
+		return ""
+	}
+	value := ""
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == key {
+			// Later settings take precedence
+			value = strings.TrimSpace(v)
+		}
+	}
+	return value
 }
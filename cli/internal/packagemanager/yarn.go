@@ -88,4 +88,8 @@ var nodejsYarn = PackageManager{
 
 		return packageManager.Matches(packageManager.Slug, strings.TrimSpace(string(out)))
 	},
+
+	runsPrePostScripts: func(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON) bool {
+		return true
+	},
 }
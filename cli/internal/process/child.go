@@ -40,6 +40,10 @@ var (
 	// ExitCodeError is the default error code returned when the child exits with
 	// an error without a more specific code.
 	ExitCodeError = 127
+
+	// ExitCodeTimeout is the exit code used when a child is stopped for running
+	// longer than its timeout, matching the convention of timeout(1).
+	ExitCodeTimeout = 124
 )
 
 // Child is a wrapper around a child process which can be used to send signals
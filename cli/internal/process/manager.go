@@ -26,6 +26,17 @@ func (ce *ChildExit) Error() string {
 	return fmt.Sprintf("command %s exited (%d)", ce.Command, ce.ExitCode)
 }
 
+// ChildTimeout is returned when a child process is stopped because it ran
+// for longer than its timeout
+type ChildTimeout struct {
+	Timeout time.Duration
+	Command string
+}
+
+func (ct *ChildTimeout) Error() string {
+	return fmt.Sprintf("command %s timed out after %v", ct.Command, ct.Timeout)
+}
+
 // Manager tracks all of the child processes that have been spawned
 type Manager struct {
 	done     bool
@@ -49,6 +60,14 @@ func NewManager(logger hclog.Logger) *Manager {
 // successfully, ErrClosing if the manager closed during execution, and
 // a ChildExit error if the child process exited with a non-zero exit code.
 func (m *Manager) Exec(cmd *exec.Cmd) error {
+	return m.ExecWithTimeout(cmd, 0)
+}
+
+// ExecWithTimeout behaves like Exec, except that if the child process is still
+// running after the given timeout, it is sent SIGINT, then killed if it hasn't
+// exited after a grace period, and a ChildTimeout error is returned.
+// A timeout of 0 means the child may run forever.
+func (m *Manager) ExecWithTimeout(cmd *exec.Cmd, timeout time.Duration) error {
 	m.mu.Lock()
 	if m.done {
 		m.mu.Unlock()
@@ -78,14 +97,29 @@ func (m *Manager) Exec(cmd *exec.Cmd) error {
 		m.mu.Unlock()
 		return err
 	}
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
 	err = nil
-	exitCode, ok := <-child.ExitCh()
-	if !ok {
-		err = ErrClosing
-	} else if exitCode != ExitCodeOK {
-		err = &ChildExit{
-			ExitCode: exitCode,
-			Command:  child.Command(),
+	select {
+	case exitCode, ok := <-child.ExitCh():
+		if !ok {
+			err = ErrClosing
+		} else if exitCode != ExitCodeOK {
+			err = &ChildExit{
+				ExitCode: exitCode,
+				Command:  child.Command(),
+			}
+		}
+	case <-timeoutCh:
+		// Stop waits for the child to exit, killing it after KillTimeout
+		child.Stop()
+		err = &ChildTimeout{
+			Timeout: timeout,
+			Command: child.Command(),
 		}
 	}
 
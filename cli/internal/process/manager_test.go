@@ -92,3 +92,27 @@ func TestExitCode(t *testing.T) {
 		t.Error("expected non-zero exit code , got 0")
 	}
 }
+
+func TestExecWithTimeout(t *testing.T) {
+	mgr := newManager()
+
+	start := time.Now()
+	err := mgr.ExecWithTimeout(exec.Command("sleep", "5"), 100*time.Millisecond)
+	duration := time.Since(start)
+	timeoutErr := &ChildTimeout{}
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a ChildTimeout err, got %q", err)
+	}
+	if timeoutErr.Timeout != 100*time.Millisecond {
+		t.Errorf("expected timeout of 100ms, got %v", timeoutErr.Timeout)
+	}
+	if duration >= 5*time.Second {
+		t.Errorf("expected the child to be stopped, total time was %v", duration)
+	}
+
+	// commands which finish in time are unaffected
+	err = mgr.ExecWithTimeout(exec.Command("sleep", "0.01"), time.Second)
+	if err != nil {
+		t.Errorf("expected %q to be nil", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -142,12 +144,6 @@ func (p *prune) prune(opts *opts) error {
 
 	if !util.IsYarn(ctx.PackageManager.Name) {
 		return errors.Errorf("this command is not yet implemented for %s", ctx.PackageManager.Name)
-	} else if ctx.PackageManager.Name == "nodejs-berry" {
-		if isNMLinker, err := util.IsNMLinker(p.config.Cwd.ToStringDuringMigration()); err != nil {
-			return errors.Wrap(err, "could not determine if yarn is using `nodeLinker: node-modules`")
-		} else if !isNMLinker {
-			return errors.New("only yarn v2/v3 with `nodeLinker: node-modules` is supported at this time")
-		}
 	}
 
 	p.ui.Output(fmt.Sprintf("Generating pruned monorepo for %v in %v", ui.Bold(opts.scope), ui.Bold(outDir.ToString())))
@@ -240,6 +236,10 @@ func (p *prune) prune(opts *opts) error {
 		}
 	}
 
+	if ctx.BerryLockfile != nil {
+		return p.writeBerryOutput(ctx, targets, outDir, opts)
+	}
+
 	var b bytes.Buffer
 	yamlEncoder := yaml.NewEncoder(&b)
 	yamlEncoder.SetIndent(2)
@@ -300,3 +300,89 @@ func (p *prune) prune(opts *opts) error {
 	}
 	return nil
 }
+
+// _berryInstallFiles are the files, relative to the repository root, that yarn v2+ needs in order
+// to install. The PnP loader files (.pnp.cjs, .pnp.loader.mjs, .pnp.data.json) are deliberately
+// absent: they describe the full monorepo and must be regenerated by running "yarn install"
+// in the pruned output.
+var _berryInstallFiles = []string{
+	".yarnrc.yml",
+	filepath.Join(".yarn", "releases"),
+	filepath.Join(".yarn", "plugins"),
+}
+
+// writeBerryOutput writes the pruned yarn v2+ lockfile, along with any patches it references and
+// the yarn configuration necessary to install it.
+func (p *prune) writeBerryOutput(ctx *context.Context, targets []interface{}, outDir fs.AbsolutePath, opts *opts) error {
+	descriptorSet := make(util.Set)
+	for descriptor := range p.config.RootPackageJSON.SubBerryLockfile {
+		descriptorSet.Add(descriptor)
+	}
+	if descriptor, ok := ctx.BerryLockfile.WorkspaceDescriptor("."); ok {
+		descriptorSet.Add(descriptor)
+	}
+	for _, internalDep := range targets {
+		if internalDep == ctx.RootNode {
+			continue
+		}
+		pkg := ctx.PackageInfos[internalDep]
+		if descriptor, ok := ctx.BerryLockfile.WorkspaceDescriptor(pkg.Dir); ok {
+			descriptorSet.Add(descriptor)
+		}
+		for descriptor := range pkg.SubBerryLockfile {
+			descriptorSet.Add(descriptor)
+		}
+	}
+	descriptors := descriptorSet.UnsafeListOfStrings()
+	sort.Strings(descriptors)
+
+	// Everything needed for "yarn install" goes into "json" when using --docker, since that is
+	// the layer the install happens in. The full source tree needs it as well.
+	installDirs := []fs.AbsolutePath{outDir}
+	if opts.docker {
+		installDirs = []fs.AbsolutePath{outDir.Join("json"), outDir.Join("full")}
+	}
+	installFiles := append([]string{}, _berryInstallFiles...)
+	installFiles = append(installFiles, ctx.BerryLockfile.PatchFiles(descriptors)...)
+	for _, installFile := range installFiles {
+		from := p.config.Cwd.Join(installFile)
+		if !fs.PathExists(from.ToString()) {
+			continue
+		}
+		for _, installDir := range installDirs {
+			to := installDir.Join(installFile)
+			if err := to.EnsureDir(); err != nil {
+				return errors.Wrapf(err, "failed to create folder for %v", installFile)
+			}
+			if err := fs.RecursiveCopy(from.ToString(), to.ToString()); err != nil {
+				return errors.Wrapf(err, "failed to copy %v", installFile)
+			}
+		}
+	}
+
+	lockfile, err := outDir.Join("yarn.lock").Create()
+	if err != nil {
+		return errors.Wrap(err, "failed to create sub-lockfile")
+	}
+	if err := ctx.BerryLockfile.Subset(descriptors).Encode(lockfile); err != nil {
+		_ = lockfile.Close()
+		return errors.Wrap(err, "failed to materialize sub-lockfile. This can happen if your lockfile contains merge conflicts or is somehow corrupted. Please report this if it occurs")
+	}
+	if err := lockfile.Close(); err != nil {
+		return errors.Wrap(err, "failed to write sub-lockfile")
+	}
+
+	isNMLinker, err := util.IsNMLinker(p.config.Cwd.ToStringDuringMigration())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to read yarn configuration")
+	}
+	// Without a .yarnrc.yml, yarn uses its default linker, which is Plug'n'Play
+	if !isNMLinker {
+		installDir := outDir
+		if opts.docker {
+			installDir = outDir.Join("json")
+		}
+		p.ui.Output(fmt.Sprintf("Plug'n'Play install detected. Run %v in %v to generate a pruned .pnp.cjs", ui.Bold("yarn install"), ui.Bold(installDir.ToString())))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package prune
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+// setupBerryRepo copies the Plug'n'Play fixture into a temporary directory, and
+// makes it the working directory
+func setupBerryRepo(t *testing.T) fs.AbsolutePath {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir()).Join("repo")
+	assert.NilError(t, fs.RecursiveCopy(filepath.Join("testdata", "berry"), repoRoot.ToString()), "RecursiveCopy")
+
+	cwd, err := os.Getwd()
+	assert.NilError(t, err, "Getwd")
+	assert.NilError(t, os.Chdir(repoRoot.ToString()), "Chdir")
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+	return repoRoot
+}
+
+func newBerryPrune(t *testing.T, repoRoot fs.AbsolutePath) (*prune, *cli.MockUi) {
+	rootPackageJSON, err := fs.ReadPackageJSON(repoRoot.Join("package.json").ToString())
+	assert.NilError(t, err, "ReadPackageJSON")
+	ui := cli.NewMockUi()
+	return &prune{
+		logger: hclog.NewNullLogger(),
+		ui:     ui,
+		config: &config.Config{
+			Logger:          hclog.NewNullLogger(),
+			Cwd:             repoRoot,
+			RootPackageJSON: rootPackageJSON,
+		},
+	}, ui
+}
+
+func Test_pruneBerryPnP(t *testing.T) {
+	repoRoot := setupBerryRepo(t)
+	p, ui := newBerryPrune(t, repoRoot)
+
+	assert.NilError(t, p.prune(&opts{scope: "web", outputDir: "out"}), "prune")
+	outDir := repoRoot.Join("out")
+	lockfile, err := outDir.Join("yarn.lock").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Assert(t, strings.Contains(string(lockfile), "left-pad@npm:^1.3.0"))
+	assert.Assert(t, !strings.Contains(string(lockfile), "is-number"))
+	assert.Assert(t, outDir.Join(".yarnrc.yml").FileExists())
+
+	// The loader describes the full monorepo, so it is left for yarn to generate
+	assert.Assert(t, !outDir.Join(".pnp.cjs").FileExists())
+	assert.Assert(t, strings.Contains(ui.OutputWriter.String(), "Run yarn install in "+outDir.ToString()), ui.OutputWriter.String())
+}
+
+func Test_pruneBerryPnPDocker(t *testing.T) {
+	repoRoot := setupBerryRepo(t)
+	p, ui := newBerryPrune(t, repoRoot)
+
+	assert.NilError(t, p.prune(&opts{scope: "web", outputDir: "out", docker: true}), "prune")
+	jsonDir := repoRoot.Join("out", "json")
+	assert.Assert(t, jsonDir.Join(".yarnrc.yml").FileExists())
+	assert.Assert(t, !jsonDir.Join(".pnp.cjs").FileExists())
+	assert.Assert(t, !repoRoot.Join("out", "full", ".pnp.cjs").FileExists())
+	assert.Assert(t, strings.Contains(ui.OutputWriter.String(), "Run yarn install in "+jsonDir.ToString()), ui.OutputWriter.String())
+}
+
+func Test_pruneBerryInvalidYarnRC(t *testing.T) {
+	repoRoot := setupBerryRepo(t)
+	assert.NilError(t, repoRoot.Join(".yarnrc.yml").WriteFile([]byte("nodeLinker: [\n"), 0644), "WriteFile")
+	p, _ := newBerryPrune(t, repoRoot)
+
+	err := p.prune(&opts{scope: "web", outputDir: "out"})
+	assert.ErrorContains(t, err, ".yarnrc.yml")
+}
@@ -0,0 +1 @@
+// The Plug'n'Play loader for the whole monorepo
@@ -0,0 +1,228 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a lexical token in a query
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenUnion
+	tokenExcept
+	tokenIntersect
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// isWordRune reports whether r may appear in an unquoted word. Words cover package
+// names, such as @scope/pkg, task ids, such as web#build or //#lint, globs and paths.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	return strings.ContainsRune("_-./@#*:~!$%&=?[]{}", r)
+}
+
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case r == '+':
+			tokens = append(tokens, token{tokenUnion, "+", i})
+			i++
+		case r == '^':
+			tokens = append(tokens, token{tokenIntersect, "^", i})
+			i++
+		// A leading - is the except operator. Inside a word, it is part of the name.
+		case r == '-':
+			tokens = append(tokens, token{tokenExcept, "-", i})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string starting at position %v", i)
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[i+1 : end]), i})
+			i = end + 1
+		case isWordRune(r):
+			end := i
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			text := string(runes[i:end])
+			kind := tokenWord
+			switch text {
+			case "union":
+				kind = tokenUnion
+			case "except":
+				kind = tokenExcept
+			case "intersect":
+				kind = tokenIntersect
+			}
+			tokens = append(tokens, token{kind, text, i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %v", r, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(runes)}), nil
+}
+
+// expr is a node in a parsed query
+type expr interface {
+	eval(u *Universe) (Result, error)
+}
+
+// wordExpr names packages or tasks, optionally with * wildcards
+type wordExpr struct {
+	word string
+}
+
+// setExpr combines the results of two expressions
+type setExpr struct {
+	op    tokenKind
+	left  expr
+	right expr
+}
+
+// callExpr applies a function to its arguments. Arguments are either
+// expressions or, for depths and patterns, plain words.
+type callExpr struct {
+	name string
+	args []expr
+	pos  int
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query expression
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %v", tok.text, tok.pos)
+	}
+	return &Query{root: e}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		if tok.kind == tokenEOF {
+			return fmt.Errorf("expected %q at end of query", text)
+		}
+		return fmt.Errorf("expected %q at position %v, found %q", text, tok.pos, tok.text)
+	}
+	return nil
+}
+
+// parseExpr parses binary set operations, which all have the same precedence
+// and associate to the left. Use parentheses to group them differently.
+func (p *parser) parseExpr() (expr, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().kind
+		if op != tokenUnion && op != tokenExcept && op != tokenIntersect {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &setExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseTerm() (expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenWord:
+		if p.peek().kind != tokenLParen {
+			return &wordExpr{word: tok.text}, nil
+		}
+		p.next()
+		call := &callExpr{name: tok.text, pos: tok.pos}
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return call, nil
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %v", tok.text, tok.pos)
+	}
+}
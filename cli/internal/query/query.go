@@ -0,0 +1,423 @@
+// Package query implements a small language, in the spirit of bazel query, for
+// asking questions about the package graph and the task graph.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// Universe holds the graphs that queries are evaluated against
+type Universe struct {
+	// Packages is the package dependency graph. Edges point from a package to its dependencies.
+	Packages *dag.AcyclicGraph
+	// Tasks is the task graph, whose vertices are package#task ids. Edges point
+	// from a task to the tasks it depends on. Placeholder root vertices are ignored.
+	Tasks *dag.AcyclicGraph
+	// Owner returns the package containing the given repo-relative file
+	Owner func(file string) (string, error)
+}
+
+// Result is the ordered, de-duplicated list of packages and task ids selected by a query
+type Result []string
+
+// Query is a parsed query expression
+type Query struct {
+	root expr
+}
+
+// Eval runs the query against the given graphs
+func (q *Query) Eval(u *Universe) (Result, error) {
+	return q.root.eval(u)
+}
+
+// resultBuilder accumulates a Result, dropping duplicates
+type resultBuilder struct {
+	seen   map[string]bool
+	result Result
+}
+
+func newResultBuilder() *resultBuilder {
+	return &resultBuilder{seen: make(map[string]bool), result: Result{}}
+}
+
+func (b *resultBuilder) add(ids ...string) {
+	for _, id := range ids {
+		if !b.seen[id] {
+			b.seen[id] = true
+			b.result = append(b.result, id)
+		}
+	}
+}
+
+func (r Result) set() map[string]bool {
+	s := make(map[string]bool, len(r))
+	for _, id := range r {
+		s[id] = true
+	}
+	return s
+}
+
+// graphFor returns the graph that contains the given package or task
+func (u *Universe) graphFor(id string) *dag.AcyclicGraph {
+	if u.Tasks.HasVertex(id) {
+		return u.Tasks
+	}
+	return u.Packages
+}
+
+// vertices lists the packages and then the tasks in the universe, in a stable order
+func (u *Universe) vertices() []string {
+	ids := []string{}
+	for _, graph := range []*dag.AcyclicGraph{u.Packages, u.Tasks} {
+		names := []string{}
+		for _, v := range graph.Vertices() {
+			if name := dag.VertexName(v); !strings.Contains(name, core.ROOT_NODE_NAME) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		ids = append(ids, names...)
+	}
+	return ids
+}
+
+// neighbours returns the sorted dependencies (or dependents, if reverse is set) of id
+func (u *Universe) neighbours(id string, reverse bool) []string {
+	graph := u.graphFor(id)
+	var edges dag.Set
+	if reverse {
+		edges = graph.UpEdges(id)
+	} else {
+		edges = graph.DownEdges(id)
+	}
+	names := []string{}
+	for _, v := range edges {
+		if name := dag.VertexName(v); !strings.Contains(name, core.ROOT_NODE_NAME) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// walk returns ids and everything reachable from them, nearest first, up to
+// maxDepth edges away. A negative maxDepth is unlimited.
+func (u *Universe) walk(ids Result, reverse bool, maxDepth int) Result {
+	b := newResultBuilder()
+	b.add(ids...)
+	frontier := ids
+	for depth := 0; len(frontier) > 0 && (maxDepth < 0 || depth < maxDepth); depth++ {
+		next := Result{}
+		for _, id := range frontier {
+			for _, n := range u.neighbours(id, reverse) {
+				if !b.seen[n] {
+					b.add(n)
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+	return b.result
+}
+
+// somePath returns a shortest path from any of from to any of to, following dependencies
+func (u *Universe) somePath(from Result, to Result) Result {
+	targets := to.set()
+	parents := make(map[string]string)
+	visited := make(map[string]bool)
+	queue := []string{}
+	for _, id := range from {
+		if !visited[id] {
+			visited[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if targets[id] {
+			path := Result{id}
+			for {
+				parent, ok := parents[id]
+				if !ok {
+					break
+				}
+				path = append(Result{parent}, path...)
+				id = parent
+			}
+			return path
+		}
+		for _, n := range u.neighbours(id, false) {
+			if !visited[n] {
+				visited[n] = true
+				parents[n] = id
+				queue = append(queue, n)
+			}
+		}
+	}
+	return Result{}
+}
+
+func (w *wordExpr) eval(u *Universe) (Result, error) {
+	b := newResultBuilder()
+	if strings.Contains(w.word, "*") {
+		pattern := regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(w.word), `\*`, ".*") + "$")
+		for _, id := range u.vertices() {
+			if pattern.MatchString(id) {
+				b.add(id)
+			}
+		}
+	} else if !strings.Contains(w.word, core.ROOT_NODE_NAME) && (u.Packages.HasVertex(w.word) || u.Tasks.HasVertex(w.word)) {
+		b.add(w.word)
+	}
+	if len(b.result) == 0 {
+		return nil, fmt.Errorf("%q does not match any package or task", w.word)
+	}
+	return b.result, nil
+}
+
+func (s *setExpr) eval(u *Universe) (Result, error) {
+	left, err := s.left.eval(u)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.right.eval(u)
+	if err != nil {
+		return nil, err
+	}
+	b := newResultBuilder()
+	switch s.op {
+	case tokenUnion:
+		b.add(left...)
+		b.add(right...)
+	case tokenExcept:
+		exclude := right.set()
+		for _, id := range left {
+			if !exclude[id] {
+				b.add(id)
+			}
+		}
+	case tokenIntersect:
+		include := right.set()
+		for _, id := range left {
+			if include[id] {
+				b.add(id)
+			}
+		}
+	}
+	return b.result, nil
+}
+
+// function describes one of the functions available in queries
+type function struct {
+	// minArgs and maxArgs bound the number of arguments
+	minArgs int
+	maxArgs int
+	eval    func(u *Universe, c *callExpr) (Result, error)
+}
+
+// functions is populated in init, since evaluating a call refers back to it
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"deps":     {1, 2, evalDeps(false)},
+		"rdeps":    {1, 2, evalDeps(true)},
+		"allpaths": {2, 2, evalAllPaths},
+		"somepath": {2, 2, evalSomePath},
+		"filter":   {2, 2, evalFilter},
+		"packages": {1, 1, evalPackages},
+		"tasks":    {1, 2, evalTasks},
+		"owner":    {1, 1, evalOwner},
+	}
+}
+
+func (c *callExpr) eval(u *Universe) (Result, error) {
+	fn, ok := functions[c.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %v", c.name, c.pos)
+	}
+	if len(c.args) < fn.minArgs || len(c.args) > fn.maxArgs {
+		if fn.minArgs == fn.maxArgs {
+			return nil, fmt.Errorf("%v() takes %v arguments, got %v", c.name, fn.minArgs, len(c.args))
+		}
+		return nil, fmt.Errorf("%v() takes %v to %v arguments, got %v", c.name, fn.minArgs, fn.maxArgs, len(c.args))
+	}
+	return fn.eval(u, c)
+}
+
+// word returns the literal text of argument i, for arguments which aren't queries
+func (c *callExpr) word(i int) (string, error) {
+	w, ok := c.args[i].(*wordExpr)
+	if !ok {
+		return "", fmt.Errorf("argument %v of %v() must be a word", i+1, c.name)
+	}
+	return w.word, nil
+}
+
+// depth parses the optional depth argument at index i. -1 means unlimited.
+func (c *callExpr) depth(i int) (int, error) {
+	if len(c.args) <= i {
+		return -1, nil
+	}
+	w, err := c.word(i)
+	if err != nil {
+		return 0, err
+	}
+	depth, err := strconv.Atoi(w)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("depth for %v() must be a non-negative integer, got %q", c.name, w)
+	}
+	return depth, nil
+}
+
+// evalDeps implements deps(x[, depth]), which selects x and everything it depends
+// on, and rdeps(x[, depth]), which selects x and everything that depends on it
+func evalDeps(reverse bool) func(u *Universe, c *callExpr) (Result, error) {
+	return func(u *Universe, c *callExpr) (Result, error) {
+		ids, err := c.args[0].eval(u)
+		if err != nil {
+			return nil, err
+		}
+		depth, err := c.depth(1)
+		if err != nil {
+			return nil, err
+		}
+		return u.walk(ids, reverse, depth), nil
+	}
+}
+
+// evalAllPaths implements allpaths(from, to), which selects everything on any
+// dependency path from from to to
+func evalAllPaths(u *Universe, c *callExpr) (Result, error) {
+	from, err := c.args[0].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.args[1].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	reachesTo := u.walk(to, true, -1).set()
+	b := newResultBuilder()
+	for _, id := range u.walk(from, false, -1) {
+		if reachesTo[id] {
+			b.add(id)
+		}
+	}
+	return b.result, nil
+}
+
+// evalSomePath implements somepath(from, to), which selects a single dependency
+// path from from to to, in order
+func evalSomePath(u *Universe, c *callExpr) (Result, error) {
+	from, err := c.args[0].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.args[1].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	return u.somePath(from, to), nil
+}
+
+// evalFilter implements filter(pattern, x), which keeps the members of x whose
+// names match the regular expression pattern
+func evalFilter(u *Universe, c *callExpr) (Result, error) {
+	pattern, err := c.word(0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for filter(): %w", err)
+	}
+	ids, err := c.args[1].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	b := newResultBuilder()
+	for _, id := range ids {
+		if re.MatchString(id) {
+			b.add(id)
+		}
+	}
+	return b.result, nil
+}
+
+// evalPackages implements packages(x), which replaces the tasks in x with the
+// packages they belong to
+func evalPackages(u *Universe, c *callExpr) (Result, error) {
+	ids, err := c.args[0].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	b := newResultBuilder()
+	for _, id := range ids {
+		if u.Tasks.HasVertex(id) {
+			pkg, _ := util.GetPackageTaskFromId(id)
+			b.add(pkg)
+		} else {
+			b.add(id)
+		}
+	}
+	return b.result, nil
+}
+
+// evalTasks implements tasks(x[, name]), which replaces the packages in x with
+// their tasks, optionally only those called name
+func evalTasks(u *Universe, c *callExpr) (Result, error) {
+	ids, err := c.args[0].eval(u)
+	if err != nil {
+		return nil, err
+	}
+	name := ""
+	if len(c.args) > 1 {
+		if name, err = c.word(1); err != nil {
+			return nil, err
+		}
+	}
+	tasksByPackage := make(map[string][]string)
+	for _, id := range u.vertices() {
+		if u.Tasks.HasVertex(id) {
+			pkg, task := util.GetPackageTaskFromId(id)
+			if name == "" || task == name {
+				tasksByPackage[pkg] = append(tasksByPackage[pkg], id)
+			}
+		}
+	}
+	b := newResultBuilder()
+	for _, id := range ids {
+		if u.Tasks.HasVertex(id) {
+			if _, task := util.GetPackageTaskFromId(id); name == "" || task == name {
+				b.add(id)
+			}
+		} else {
+			b.add(tasksByPackage[id]...)
+		}
+	}
+	return b.result, nil
+}
+
+// evalOwner implements owner(file), which selects the package containing file
+func evalOwner(u *Universe, c *callExpr) (Result, error) {
+	file, err := c.word(0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := u.Owner(file)
+	if err != nil {
+		return nil, err
+	}
+	return Result{pkg}, nil
+}
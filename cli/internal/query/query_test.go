@@ -0,0 +1,156 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/core"
+)
+
+// testUniverse models a repo where web and docs depend on ui, which depends on
+// config, and build depends on the build of each dependency.
+func testUniverse() *Universe {
+	packages := &dag.AcyclicGraph{}
+	for _, pkg := range []string{"//", "web", "docs", "ui", "config", core.ROOT_NODE_NAME} {
+		packages.Add(pkg)
+	}
+	packages.Connect(dag.BasicEdge("web", "ui"))
+	packages.Connect(dag.BasicEdge("docs", "ui"))
+	packages.Connect(dag.BasicEdge("ui", "config"))
+	packages.Connect(dag.BasicEdge("config", core.ROOT_NODE_NAME))
+
+	tasks := &dag.AcyclicGraph{}
+	for _, task := range []string{"web#build", "docs#build", "ui#build", "config#build", "web#test", "ui#test", "//#lint", core.ROOT_NODE_NAME, core.ROOT_NODE_NAME + "#build"} {
+		tasks.Add(task)
+	}
+	tasks.Connect(dag.BasicEdge("web#build", "ui#build"))
+	tasks.Connect(dag.BasicEdge("docs#build", "ui#build"))
+	tasks.Connect(dag.BasicEdge("ui#build", "config#build"))
+	tasks.Connect(dag.BasicEdge("web#test", "web#build"))
+	tasks.Connect(dag.BasicEdge("ui#test", "ui#build"))
+	tasks.Connect(dag.BasicEdge("config#build", core.ROOT_NODE_NAME))
+	tasks.Connect(dag.BasicEdge("//#lint", core.ROOT_NODE_NAME))
+
+	return &Universe{
+		Packages: packages,
+		Tasks:    tasks,
+		Owner: func(file string) (string, error) {
+			for _, pkg := range []string{"web", "docs", "ui", "config"} {
+				if strings.HasPrefix(file, "packages/"+pkg+"/") {
+					return pkg, nil
+				}
+			}
+			return "//", nil
+		},
+	}
+}
+
+func TestEval(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected Result
+	}{
+		{"web", Result{"web"}},
+		{"deps(web)", Result{"web", "ui", "config"}},
+		{"deps(web, 1)", Result{"web", "ui"}},
+		{"deps(web, 0)", Result{"web"}},
+		{"rdeps(config)", Result{"config", "ui", "docs", "web"}},
+		{"rdeps(ui#build)", Result{"ui#build", "docs#build", "ui#test", "web#build", "web#test"}},
+		{"deps(web#test)", Result{"web#test", "web#build", "ui#build", "config#build"}},
+		{"allpaths(web#test, config#build)", Result{"web#test", "web#build", "ui#build", "config#build"}},
+		{"allpaths(web, docs)", Result{}},
+		{"somepath(web#test, config#build)", Result{"web#test", "web#build", "ui#build", "config#build"}},
+		{"somepath(config, web)", Result{}},
+		{"rdeps(config) - web", Result{"config", "ui", "docs"}},
+		{"rdeps(config) except web except docs", Result{"config", "ui"}},
+		{"deps(web) ^ deps(docs)", Result{"ui", "config"}},
+		{"deps(web) intersect deps(docs)", Result{"ui", "config"}},
+		{"web + docs + web", Result{"web", "docs"}},
+		{"web union (docs - docs)", Result{"web"}},
+		{"*#test", Result{"ui#test", "web#test"}},
+		{"filter(\"^w\", deps(web#test))", Result{"web#test", "web#build"}},
+		{"filter(test, *)", Result{"ui#test", "web#test"}},
+		{"packages(rdeps(config#build))", Result{"config", "ui", "docs", "web"}},
+		{"tasks(ui)", Result{"ui#build", "ui#test"}},
+		{"tasks(ui + web#test, build)", Result{"ui#build"}},
+		{"rdeps(tasks(owner(\"packages/ui/src/index.ts\"), build))", Result{"ui#build", "docs#build", "ui#test", "web#build", "web#test"}},
+		{"owner('README.md')", Result{"//"}},
+		{"//#lint", Result{"//#lint"}},
+		// Names containing "-" are words, not subtraction
+		{"my-pkg-a - my-pkg-b", Result{"my-pkg-a"}},
+	}
+	u := testUniverse()
+	u.Packages.Add("my-pkg-a")
+	u.Packages.Add("my-pkg-b")
+	for _, tc := range cases {
+		q, err := Parse(tc.query)
+		if err != nil {
+			t.Errorf("%v: parse error: %v", tc.query, err)
+			continue
+		}
+		got, err := q.Eval(u)
+		if err != nil {
+			t.Errorf("%v: eval error: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%v: got %v, want %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{"", "unexpected end of query"},
+		{"deps(web", `expected ")" at end of query`},
+		{"web docs", `unexpected "docs" at position 4`},
+		{"deps(web,)", "unexpected \")\" at position 9"},
+		{"'web", "unterminated string starting at position 0"},
+		{"web | docs", "unexpected character '|' at position 4"},
+		{"nope", `"nope" does not match any package or task`},
+		{"nope#*", `"nope#*" does not match any package or task`},
+		{"___ROOT___", `"___ROOT___" does not match any package or task`},
+		{"___ROOT___#build", `"___ROOT___#build" does not match any package or task`},
+		{"rpaths(web)", `unknown function "rpaths" at position 0`},
+		{"deps(web, -1)", `unexpected "-" at position 10`},
+		{"deps(web, two)", `depth for deps() must be a non-negative integer, got "two"`},
+		{"deps(web, 1, 2)", "deps() takes 1 to 2 arguments, got 3"},
+		{"somepath(web)", "somepath() takes 2 arguments, got 1"},
+		{"filter(deps(web), web)", "argument 1 of filter() must be a word"},
+		{"filter('(', web)", "invalid pattern for filter()"},
+	}
+	u := testUniverse()
+	for _, tc := range cases {
+		q, err := Parse(tc.query)
+		if err == nil {
+			_, err = q.Eval(u)
+		}
+		if err == nil {
+			t.Errorf("%v: expected an error", tc.query)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: got error %v, want %v", tc.query, err, tc.err)
+		}
+	}
+}
+
+func TestUnknownOwner(t *testing.T) {
+	u := testUniverse()
+	u.Owner = func(file string) (string, error) {
+		return "", fmt.Errorf("cannot read %v", file)
+	}
+	q, err := Parse("owner(x)")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := q.Eval(u); err == nil || err.Error() != "cannot read x" {
+		t.Errorf("got error %v, want cannot read x", err)
+	}
+}
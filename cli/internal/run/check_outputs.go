@@ -0,0 +1,173 @@
+package run
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/cli"
+	turbofs "github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/globby"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/ui"
+)
+
+// _maxUndeclaredOutputs is how many undeclared outputs are listed for a task,
+// so that a task which writes thousands of files doesn't flood the terminal
+const _maxUndeclaredOutputs = 10
+
+// packageLocks runs the tasks in each package one at a time, so that the files that
+// one task writes aren't blamed on another task in the same package
+type packageLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock waits until no other task in the package holds the lock, and takes it. The
+// returned function releases it.
+func (p *packageLocks) lock(pkgDir string) func() {
+	p.mu.Lock()
+	if p.locks == nil {
+		p.locks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := p.locks[pkgDir]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[pkgDir] = lock
+	}
+	p.mu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// fileStamp is what a package snapshot records about each file
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// packageSnapshot records every file in a package directory, keyed by
+// repo-relative unix-style path
+type packageSnapshot map[string]fileStamp
+
+// snapshotPackage records the size and modification time of the files in a package.
+// node_modules, .git, the .turbo directory where logs are written, and nested
+// packages are left out.
+func snapshotPackage(repoRoot turbofs.AbsolutePath, pkgDir string) (packageSnapshot, error) {
+	snapshot := make(packageSnapshot)
+	root := repoRoot.Join(pkgDir).ToString()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			switch d.Name() {
+			case "node_modules", ".git", ".turbo":
+				return filepath.SkipDir
+			}
+			if turbofs.FileExists(filepath.Join(path, "package.json")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			// The file was removed while we were walking
+			return nil
+		} else if err != nil {
+			return err
+		}
+		relativePath, err := repoRoot.RelativePathString(path)
+		if err != nil {
+			return err
+		}
+		snapshot[filepath.ToSlash(relativePath)] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %v: %w", pkgDir, err)
+	}
+	return snapshot, nil
+}
+
+// changedFiles lists the files which were created or modified between two snapshots
+func changedFiles(before packageSnapshot, after packageSnapshot) []string {
+	changed := []string{}
+	for path, stamp := range after {
+		if previous, ok := before[path]; !ok || previous != stamp {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// checkOutputs compares the files that a task wrote against its declared outputs.
+// It returns the changed files which no output matches, and the outputs which
+// match no files at all.
+func checkOutputs(repoRoot turbofs.AbsolutePath, pt *nodes.PackageTask, changed []string) ([]string, []string, error) {
+	declared := make(map[string]bool)
+	unmatched := []string{}
+	for _, output := range pt.TaskDefinition.Outputs {
+		files, err := globby.GlobFiles(repoRoot.ToStringDuringMigration(), []string{filepath.Join(pt.Pkg.Dir, output)}, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			unmatched = append(unmatched, output)
+		}
+		for _, file := range files {
+			relativePath, err := repoRoot.RelativePathString(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			declared[filepath.ToSlash(relativePath)] = true
+		}
+	}
+	undeclared := []string{}
+	for _, path := range changed {
+		if !declared[path] {
+			undeclared = append(undeclared, path)
+		}
+	}
+	return undeclared, unmatched, nil
+}
+
+// reportOutputs warns about the files that a task wrote outside of its declared
+// outputs, which won't be restored from the cache, and about outputs which
+// match nothing. before is the snapshot taken before the task ran.
+func (e *execContext) reportOutputs(pt *nodes.PackageTask, before packageSnapshot, targetUi cli.Ui) {
+	after, err := snapshotPackage(e.repoRoot, pt.Pkg.Dir)
+	if err != nil {
+		targetUi.Warn(ui.Dim(fmt.Sprintf("could not check outputs: %v", err)))
+		return
+	}
+	undeclared, unmatched, err := checkOutputs(e.repoRoot, pt, changedFiles(before, after))
+	if err != nil {
+		targetUi.Warn(ui.Dim(fmt.Sprintf("could not check outputs: %v", err)))
+		return
+	}
+	if len(undeclared) > 0 {
+		targetUi.Warn(fmt.Sprintf("wrote %v files which aren't covered by the task's outputs, and won't be restored from the cache:", len(undeclared)))
+		for i, path := range undeclared {
+			if i == _maxUndeclaredOutputs {
+				targetUi.Warn(fmt.Sprintf("  ...and %v more", len(undeclared)-i))
+				break
+			}
+			targetUi.Warn("  " + path)
+		}
+	}
+	for _, output := range unmatched {
+		targetUi.Warn(fmt.Sprintf("the output %q matched no files", output))
+	}
+}
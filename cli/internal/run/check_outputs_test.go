@@ -0,0 +1,152 @@
+package run
+
+import (
+	gocontext "context"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/colorcache"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/process"
+	"github.com/vercel/turborepo/cli/internal/runcache"
+	"github.com/vercel/turborepo/cli/internal/taskhash"
+	"github.com/vercel/turborepo/cli/internal/util"
+	"gotest.tools/v3/assert"
+)
+
+func Test_checkOutputs(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	write := func(path string, contents string) {
+		file := repoRoot.Join("web", path)
+		assert.NilError(t, file.EnsureDir(), "EnsureDir")
+		assert.NilError(t, file.WriteFile([]byte(contents), 0644), "WriteFile")
+	}
+	write("package.json", "{}")
+	write("src/index.ts", "export {}")
+	write("dist/stale.js", "old")
+	write("README.md", "docs")
+
+	before, err := snapshotPackage(repoRoot, "web")
+	assert.NilError(t, err, "snapshotPackage")
+	assert.Equal(t, len(before), 4)
+
+	// Make sure that rewritten files get a different modification time
+	time.Sleep(10 * time.Millisecond)
+	write("dist/index.js", "built")
+	write("dist/stale.js", "rebuilt")
+	write("generated/types.d.ts", "declare const x: number")
+	write("README.md", "docs")
+	write(".turbo/turbo-build.log", "log")
+	write("node_modules/.cache/file", "cache")
+	write("nested/package.json", "{}")
+	write("nested/dist/index.js", "built")
+
+	after, err := snapshotPackage(repoRoot, "web")
+	assert.NilError(t, err, "snapshotPackage")
+	changed := changedFiles(before, after)
+	assert.DeepEqual(t, changed, []string{"web/README.md", "web/dist/index.js", "web/dist/stale.js", "web/generated/types.d.ts"})
+
+	pt := &nodes.PackageTask{
+		TaskID:         "web#build",
+		Pkg:            &fs.PackageJSON{Dir: "web"},
+		TaskDefinition: &fs.TaskDefinition{Outputs: []string{"dist/**", "storybook-static/**"}},
+	}
+	undeclared, unmatched, err := checkOutputs(repoRoot, pt, changed)
+	assert.NilError(t, err, "checkOutputs")
+	assert.DeepEqual(t, undeclared, []string{"web/README.md", "web/generated/types.d.ts"})
+	assert.DeepEqual(t, unmatched, []string{"storybook-static/**"})
+}
+
+func Test_execCheckOutputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the task scripts need sh")
+	}
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	// Tasks run in their package directory, relative to the working directory
+	cwd, err := os.Getwd()
+	assert.NilError(t, err, "Getwd")
+	assert.NilError(t, os.Chdir(repoRoot.ToString()), "Chdir")
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+	assert.NilError(t, repoRoot.Join("web", "package.json").EnsureDir(), "EnsureDir")
+	assert.NilError(t, repoRoot.Join("web", "package.json").WriteFile([]byte("{}"), 0644), "WriteFile")
+
+	// Each task writes its outputs, then keeps running while the other task writes its own
+	pkg := &fs.PackageJSON{
+		Name: "web",
+		Dir:  "web",
+		Scripts: map[string]string{
+			"build": "mkdir -p dist && echo built > dist/index.js && sleep 0.3",
+			"lint":  "mkdir -p reports && echo clean > reports/lint.txt && sleep 0.3",
+		},
+	}
+	pipeline := fs.Pipeline{
+		"build": fs.TaskDefinition{Outputs: []string{"dist/**"}, ShouldCache: true},
+		"lint":  fs.TaskDefinition{Outputs: []string{"reports/**"}, ShouldCache: true},
+	}
+	packageInfos := map[interface{}]*fs.PackageJSON{"web": pkg}
+	hashes := taskhash.NewTracker("___ROOT___", "global-hash", pipeline, packageInfos, nil)
+	assert.NilError(t, hashes.CalculateFileHashes([]dag.Vertex{"web#build", "web#lint"}, 1, repoRoot), "CalculateFileHashes")
+
+	logger := hclog.NewNullLogger()
+	colorCache := colorcache.New()
+	rs := &runSpec{
+		Targets:      []string{"build", "lint"},
+		FilteredPkgs: util.SetFromStrings([]string{"web"}),
+		Opts: &Opts{
+			runOpts:      runOpts{checkOutputs: true},
+			runcacheOpts: runcache.Opts{SkipReads: true, SkipWrites: true},
+		},
+	}
+	mockUi := cli.NewMockUi()
+	ec := &execContext{
+		colorCache:    colorCache,
+		runState:      NewRunState(time.Now(), "", nil),
+		rs:            rs,
+		ui:            &cli.ConcurrentUi{Ui: mockUi},
+		runCache:      runcache.New(nil, repoRoot, rs.Opts.runcacheOpts, colorCache),
+		logger:        logger,
+		processes:     process.NewManager(logger),
+		taskHashes:    hashes,
+		taskDurations: loadTaskDurations(repoRoot.Join("node_modules", ".cache", "turbo")),
+		summary:       newRunSummary(time.Now(), "test", &completeGraph{}, rs),
+		previousRun:   &lastRun{},
+		repoRoot:      repoRoot,
+		taskLocks:     newTaskLocks(repoRoot, logger),
+		directScripts: &directScripts{repoRoot: repoRoot},
+		logsWritten:   make(util.Set),
+	}
+
+	wg := sync.WaitGroup{}
+	for _, task := range []string{"build", "lint"} {
+		definition := pipeline[task]
+		pt := &nodes.PackageTask{
+			TaskID:         util.GetTaskId("web", task),
+			Task:           task,
+			PackageName:    "web",
+			Pkg:            pkg,
+			TaskDefinition: &definition,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Check(t, ec.exec(gocontext.Background(), pt, make(dag.Set)))
+		}()
+	}
+	wg.Wait()
+
+	// Neither task is blamed for the other's outputs
+	assert.Assert(t, repoRoot.Join("web", "dist", "index.js").FileExists())
+	assert.Assert(t, repoRoot.Join("web", "reports", "lint.txt").FileExists())
+	warnings := mockUi.ErrorWriter.String()
+	assert.Assert(t, !strings.Contains(warnings, "aren't covered by the task's outputs"), warnings)
+}
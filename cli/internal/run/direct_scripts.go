@@ -0,0 +1,165 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/packagemanager"
+)
+
+// directScripts runs the scripts in package.json with the system's shell, rather
+// than through the package manager, which saves the package manager's startup time
+// for every task. It sets up the environment the way the package manager would.
+type directScripts struct {
+	repoRoot fs.AbsolutePath
+	// prePost is whether the package manager runs pre<script> and post<script>
+	// around each script
+	prePost bool
+	// env is the variables which identify the package manager to scripts
+	env []string
+}
+
+// _pnpFiles are the files which Yarn Plug'n'Play installs write in place of node_modules
+var _pnpFiles = []string{".pnp.cjs", ".pnp.js"}
+
+func newDirectScripts(repoRoot fs.AbsolutePath, packageManager *packagemanager.PackageManager, rootPackageJSON *fs.PackageJSON) (*directScripts, error) {
+	for _, pnpFile := range _pnpFiles {
+		// Without node_modules, scripts can only resolve their dependencies when yarn runs them
+		if repoRoot.Join(pnpFile).FileExists() {
+			return nil, fmt.Errorf("--direct-scripts doesn't support Yarn Plug'n'Play installs (found %v), running scripts with %v instead", pnpFile, packageManager.Command)
+		}
+	}
+	return &directScripts{
+		repoRoot: repoRoot,
+		prePost:  packageManager.RunsPrePostScripts(repoRoot, rootPackageJSON),
+		env:      packageManagerEnv(packageManager, rootPackageJSON),
+	}, nil
+}
+
+// packageManagerEnv returns npm_config_user_agent and npm_execpath, which scripts and
+// the tools they run use to find out which package manager is running them. Variables
+// whose value can't be determined are left out.
+func packageManagerEnv(packageManager *packagemanager.PackageManager, rootPackageJSON *fs.PackageJSON) []string {
+	env := []string{}
+	version, err := packageManager.Version(rootPackageJSON)
+	if err == nil {
+		nodeVersion := "?"
+		if out, err := exec.Command("node", "--version").Output(); err == nil {
+			nodeVersion = strings.TrimSpace(string(out))
+		}
+		env = append(env, "npm_config_user_agent="+userAgent(packageManager.Command, version, nodeVersion))
+	}
+	if path, err := exec.LookPath(packageManager.Command); err == nil {
+		// The package manager's command is usually a link to the script that it runs
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			path = target
+		}
+		env = append(env, "npm_execpath="+path)
+	}
+	return env
+}
+
+// userAgent returns the user agent that a package manager identifies itself with,
+// such as "pnpm/7.14.0 npm/? node/v16.18.0 linux x64"
+func userAgent(command string, version string, nodeVersion string) string {
+	platform := runtime.GOOS
+	if platform == "windows" {
+		platform = "win32"
+	}
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x64"
+	case "386":
+		arch = "ia32"
+	}
+	if command == "npm" {
+		return fmt.Sprintf("npm/%v node/%v %v %v workspaces/true", version, nodeVersion, platform, arch)
+	}
+	return fmt.Sprintf("%v/%v npm/? node/%v %v %v", command, version, nodeVersion, platform, arch)
+}
+
+// commands returns the commands which run a task's script, in order. The script is
+// preceded and followed by its pre and post scripts if the package has them and the
+// package manager would run them. args are only passed to the script itself. env is
+// the environment that each command starts from.
+func (d *directScripts) commands(pt *nodes.PackageTask, args []string, env []string) []*exec.Cmd {
+	script := pt.Script()
+	names := []string{script}
+	if d.prePost {
+		names = []string{"pre" + script, script, "post" + script}
+	}
+	binPath := d.binPath(pt.Pkg.Dir, env)
+	cmds := []*exec.Cmd{}
+	for _, name := range names {
+		command, ok := pt.Pkg.Scripts[name]
+		if !ok {
+			continue
+		}
+		if name == script && len(args) > 0 {
+			command += " " + quoteScriptArgs(args)
+		}
+		cmd := shellCommand(command)
+		cmd.Dir = pt.Pkg.Dir
+		cmd.Env = append(append(append([]string{}, env...), d.env...),
+			"npm_lifecycle_event="+name,
+			"npm_lifecycle_script="+pt.Pkg.Scripts[name],
+			"npm_package_name="+pt.Pkg.Name,
+			"npm_package_version="+pt.Pkg.Version,
+			"npm_package_json="+d.repoRoot.Join(pt.Pkg.Dir, "package.json").ToString(),
+			"INIT_CWD="+d.repoRoot.Join(pt.Pkg.Dir).ToString(),
+			binPath,
+		)
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// binPath returns the PATH variable for a package's scripts: the node_modules/.bin
+// directories of the package and each directory above it, up to the repository
+// root, ahead of the PATH in env
+func (d *directScripts) binPath(pkgDir string, env []string) string {
+	dirs := []string{}
+	for dir := filepath.Clean(pkgDir); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, d.repoRoot.Join(dir, "node_modules", ".bin").ToString())
+		if dir == "." {
+			break
+		}
+	}
+	// When a variable is set more than once, the last value wins
+	key, path := "PATH", ""
+	for _, pair := range env {
+		name, value, _ := strings.Cut(pair, "=")
+		// Windows variable names aren't case sensitive, and PATH is usually Path
+		if name == "PATH" || (runtime.GOOS == "windows" && strings.EqualFold(name, "PATH")) {
+			key, path = name, value
+		}
+	}
+	if path != "" {
+		dirs = append(dirs, path)
+	}
+	return key + "=" + strings.Join(dirs, string(os.PathListSeparator))
+}
+
+// quoteScriptArgs joins arguments so that the shell passes them to the script unchanged
+func quoteScriptArgs(args []string) string {
+	if runtime.GOOS != "windows" {
+		return shellquote.Join(args...)
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\"&|<>^%") {
+			quoted[i] = arg
+		} else {
+			quoted[i] = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,106 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/packagemanager"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func Test_directScripts(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	pkgDir := filepath.Join("packages", "web")
+	assert.NilError(t, repoRoot.Join(pkgDir).MkdirAll(), "MkdirAll")
+	pt := &nodes.PackageTask{
+		TaskID: "web#build",
+		Task:   "build",
+		Pkg: &fs.PackageJSON{
+			Name:    "web",
+			Version: "1.0.0",
+			Dir:     pkgDir,
+			Scripts: map[string]string{
+				"prebuild":  "echo before",
+				"build":     `echo "$npm_lifecycle_event" "$npm_package_name"`,
+				"postbuild": "echo after",
+				"pretest":   "echo unrelated",
+			},
+		},
+		TaskDefinition: &fs.TaskDefinition{},
+	}
+	env := []string{"PATH=/usr/bin", "TURBO_HASH=0123", "PATH=/bin"}
+	binPath := strings.Join([]string{
+		repoRoot.Join(pkgDir, "node_modules", ".bin").ToString(),
+		repoRoot.Join("packages", "node_modules", ".bin").ToString(),
+		repoRoot.Join("node_modules", ".bin").ToString(),
+		"/bin",
+	}, string(os.PathListSeparator))
+
+	agentEnv := "npm_config_user_agent=yarn/1.22.19 npm/? node/v16.18.0 linux x64"
+	d := &directScripts{repoRoot: repoRoot, prePost: true, env: []string{agentEnv}}
+	cmds := d.commands(pt, []string{"--mode", "it's fast"}, env)
+	assert.Equal(t, len(cmds), 3)
+	for i, event := range []string{"prebuild", "build", "postbuild"} {
+		assert.Equal(t, cmds[i].Dir, pkgDir)
+		assert.Assert(t, is.Contains(cmds[i].Env, "npm_lifecycle_event="+event))
+		assert.Assert(t, is.Contains(cmds[i].Env, "TURBO_HASH=0123"))
+		assert.Assert(t, is.Contains(cmds[i].Env, agentEnv))
+		assert.Equal(t, cmds[i].Env[len(cmds[i].Env)-1], "PATH="+binPath)
+	}
+	assert.Assert(t, is.Contains(cmds[1].Env, "npm_package_name=web"))
+	assert.Assert(t, is.Contains(cmds[1].Env, "INIT_CWD="+repoRoot.Join(pkgDir).ToString()))
+	// Arguments are only passed to the script itself
+	assert.Equal(t, cmds[0].Args[len(cmds[0].Args)-1], "echo before")
+
+	if runtime.GOOS != "windows" {
+		cmds[1].Dir = repoRoot.Join(pkgDir).ToString()
+		out, err := cmds[1].Output()
+		assert.NilError(t, err, "Output")
+		assert.Equal(t, string(out), "build web --mode it's fast\n")
+	}
+
+	d.prePost = false
+	cmds = d.commands(pt, nil, env)
+	assert.Equal(t, len(cmds), 1)
+	assert.Assert(t, is.Contains(cmds[0].Env, "npm_lifecycle_event=build"))
+}
+
+func Test_newDirectScripts(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	rootPackageJSON := &fs.PackageJSON{PackageManager: "yarn@3.2.4"}
+	pm, err := packagemanager.GetPackageManager(repoRoot, rootPackageJSON)
+	assert.NilError(t, err, "GetPackageManager")
+
+	d, err := newDirectScripts(repoRoot, pm, rootPackageJSON)
+	assert.NilError(t, err, "newDirectScripts")
+	assert.Assert(t, is.Contains(d.env, "npm_config_user_agent="+userAgent("yarn", "3.2.4", nodeVersion(t))))
+
+	// Scripts in Plug'n'Play installs need yarn to resolve their dependencies
+	assert.NilError(t, repoRoot.Join(".pnp.cjs").WriteFile([]byte{}, 0644), "WriteFile")
+	_, err = newDirectScripts(repoRoot, pm, rootPackageJSON)
+	assert.ErrorContains(t, err, ".pnp.cjs")
+}
+
+// nodeVersion returns the version of node on the PATH, as it appears in user agents
+func nodeVersion(t *testing.T) string {
+	out, err := exec.Command("node", "--version").Output()
+	if err != nil {
+		return "?"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func Test_userAgent(t *testing.T) {
+	agent := userAgent("pnpm", "7.14.0", "v16.18.0")
+	assert.Assert(t, strings.HasPrefix(agent, "pnpm/7.14.0 npm/? node/v16.18.0 "), agent)
+	agent = userAgent("npm", "8.19.2", "v16.18.0")
+	assert.Assert(t, strings.HasPrefix(agent, "npm/8.19.2 node/v16.18.0 "), agent)
+	assert.Assert(t, strings.HasSuffix(agent, " workspaces/true"), agent)
+}
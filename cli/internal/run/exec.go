@@ -0,0 +1,276 @@
+package run
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pyr-sh/dag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/colorcache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/logstreamer"
+	"github.com/vercel/turborepo/cli/internal/process"
+	"github.com/vercel/turborepo/cli/internal/scope"
+	"github.com/vercel/turborepo/cli/internal/signals"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+)
+
+// _execTaskName is the name of the task that stands in for the command in the
+// scheduler. It never appears in the pipeline or in output.
+const _execTaskName = "exec"
+
+// ExecCommand is a Command implementation that runs an arbitrary command in each package
+type ExecCommand struct {
+	Config        *config.Config
+	UI            *cli.ColoredUi
+	SignalWatcher *signals.Watcher
+}
+
+var _execCmdLong = `
+Run a command in each package in scope, without adding a script to every
+package.json. Nothing is cached.
+
+If a single argument follows '--', it is run by the shell, so it may use pipes,
+globs and other shell syntax. Otherwise, the arguments are run as a command
+directly.
+`
+
+const (
+	_execTopologicalHelp = `Run the command in a package only after it has finished in
+every package in scope which that package depends on.`
+	_execContinueHelp = `Keep running the command in other packages after it fails in one.`
+)
+
+// execOpts holds the options that control a turbo exec
+type execOpts struct {
+	concurrency     int
+	topological     bool
+	continueOnError bool
+}
+
+func getExecCmd(config *config.Config, output cli.Ui, signalWatcher *signals.Watcher) *cobra.Command {
+	opts := getDefaultOptions(config)
+	execOpts := &execOpts{concurrency: 10}
+	var flags *pflag.FlagSet
+	cmd := &cobra.Command{
+		Use:                   "turbo exec [<flags>] -- <command> [...<args>]",
+		Short:                 "Run a command in each package in your monorepo",
+		Long:                  _execCmdLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			argSplit := flags.ArgsLenAtDash()
+			if argSplit == -1 || argSplit == len(args) {
+				return errors.New("a command must be specified after --")
+			}
+			if argSplit > 0 {
+				return fmt.Errorf("unexpected arguments before --: %v", strings.Join(args[:argSplit], " "))
+			}
+			processes := process.NewManager(config.Logger.Named("processes"))
+			signalWatcher.AddOnClose(processes.Close)
+			r := &run{
+				opts:      opts,
+				config:    config,
+				ui:        output,
+				processes: processes,
+			}
+			return r.exec(args[argSplit:], execOpts)
+		},
+	}
+	flags = cmd.Flags()
+	scope.AddFlags(&opts.scopeOpts, flags)
+	flags.AddFlag(&pflag.Flag{
+		Name:     "concurrency",
+		Usage:    "Limit how many packages run the command at once. Use 1 for serial (i.e. one-at-a-time) execution.",
+		DefValue: "10",
+		Value: &util.ConcurrencyValue{
+			Value: &execOpts.concurrency,
+		},
+	})
+	flags.BoolVar(&execOpts.topological, "topological", false, _execTopologicalHelp)
+	flags.BoolVar(&execOpts.continueOnError, "continue", false, _execContinueHelp)
+	noopPersistentOptsDuringMigration(flags)
+	return cmd
+}
+
+// exec runs command in each package in scope
+func (r *run) exec(command []string, opts *execOpts) error {
+	g, rs, _, err := r.prepare(nil)
+	if err != nil {
+		return err
+	}
+	engine, err := buildExecGraph(&g.TopologicalGraph, rs.FilteredPkgs, opts.topological)
+	if err != nil {
+		return errors.Wrap(err, "error preparing engine")
+	}
+	packagesInScope := rs.FilteredPkgs.UnsafeListOfStrings()
+	sort.Strings(packagesInScope)
+	r.ui.Output(fmt.Sprintf(ui.Dim("• Packages in scope: %v"), strings.Join(packagesInScope, ", ")))
+	r.ui.Output(fmt.Sprintf("%s %s %s", ui.Dim("• Running"), ui.Dim(ui.Bold(strings.Join(command, " "))), ui.Dim(fmt.Sprintf("in %v packages", len(packagesInScope)))))
+
+	colorCache := colorcache.New()
+	startAt := time.Now()
+	var mu sync.Mutex
+	succeeded := 0
+	var failed []string
+	errs := engine.Execute(func(taskID string) error {
+		pkgName, _ := util.GetPackageTaskFromId(taskID)
+		pkg := g.PackageInfos[pkgName]
+		prefix := colorCache.PrefixColor(pkgName)("%s: ", pkgName)
+		var cmd *exec.Cmd
+		if len(command) == 1 {
+			cmd = shellCommand(command[0])
+		} else {
+			cmd = exec.Command(command[0], command[1:]...)
+		}
+		cmd.Dir = pkg.Dir
+		cmd.Env = os.Environ()
+		logger := log.New(os.Stdout, "", 0)
+		stdout := logstreamer.NewLogstreamer(logger, prefix, false)
+		stderr := logstreamer.NewLogstreamer(logger, prefix, false)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		err := r.processes.Exec(cmd)
+		_ = stdout.Close()
+		_ = stderr.Close()
+		if err != nil {
+			if errors.Is(err, process.ErrClosing) {
+				return nil
+			}
+			r.ui.Error(fmt.Sprintf("%s%s%s", prefix, ui.ERROR_PREFIX, color.RedString(" %v", err)))
+			mu.Lock()
+			failed = append(failed, pkgName)
+			mu.Unlock()
+			if !opts.continueOnError {
+				r.processes.Close()
+			}
+			return err
+		}
+		mu.Lock()
+		succeeded++
+		mu.Unlock()
+		return nil
+	}, core.ExecOpts{
+		Concurrency: opts.concurrency,
+	})
+
+	r.ui.Output("")
+	r.ui.Output(util.Sprintf("${BOLD} Packages:${BOLD_GREEN}    %v successful${RESET}${GRAY}, %v total${RESET}", succeeded, len(packagesInScope)))
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		r.ui.Output(util.Sprintf("${BOLD}   Failed:${BOLD_RED}    %v${RESET}", strings.Join(failed, ", ")))
+	}
+	r.ui.Output(util.Sprintf("${BOLD}     Time:${RESET}    %v", time.Since(startAt).Truncate(time.Millisecond)))
+	r.ui.Output("")
+
+	for _, err := range errs {
+		exitErr := &process.ChildExit{}
+		if errors.As(err, &exitErr) {
+			return exitErr
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// buildExecGraph schedules the command once in each package. If topological is set,
+// each package waits for the packages in scope that it depends on, even indirectly.
+// Packages outside of the scope never run, unlike the dependencies of tasks in turbo run.
+func buildExecGraph(topoGraph *dag.AcyclicGraph, packages util.Set, topological bool) (*core.Scheduler, error) {
+	graph := &dag.AcyclicGraph{}
+	for _, pkg := range packages {
+		graph.Add(pkg)
+	}
+	if topological {
+		for _, pkg := range packages {
+			deps, err := topoGraph.Ancestors(pkg)
+			if err != nil {
+				continue
+			}
+			for _, dep := range deps {
+				if packages.Includes(dep) {
+					graph.Connect(dag.BasicEdge(pkg, dep))
+				}
+			}
+		}
+		graph.TransitiveReduction()
+	}
+	engine := core.NewScheduler(graph)
+	topoDeps := make(util.Set)
+	if topological {
+		topoDeps.Add(_execTaskName)
+	}
+	engine.AddTask(&core.Task{Name: _execTaskName, TopoDeps: topoDeps, Deps: make(util.Set)})
+	engine.AddTask(&core.Task{Name: util.RootTaskID(_execTaskName), TopoDeps: topoDeps, Deps: make(util.Set)})
+	if err := engine.Prepare(&core.SchedulerExecutionOptions{
+		Packages:  packages.UnsafeListOfStrings(),
+		TaskNames: []string{_execTaskName},
+	}); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// shellCommand runs script with the system's shell
+func shellCommand(script string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", script)
+	}
+	return exec.Command("sh", "-c", script)
+}
+
+// Synopsis of exec command
+func (c *ExecCommand) Synopsis() string {
+	cmd := getExecCmd(c.Config, c.UI, c.SignalWatcher)
+	return cmd.Short
+}
+
+// Help returns information about the `exec` command
+func (c *ExecCommand) Help() string {
+	cmd := getExecCmd(c.Config, c.UI, c.SignalWatcher)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run executes a command in each package in scope
+func (c *ExecCommand) Run(args []string) int {
+	cmd := getExecCmd(c.Config, c.UI, c.SignalWatcher)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		exitErr := &process.ChildExit{}
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode
+		}
+		c.logError(c.Config.Logger, "", err)
+		return 1
+	}
+	return 0
+}
+
+// logError logs an error and outputs it to the UI.
+func (c *ExecCommand) logError(log hclog.Logger, prefix string, err error) {
+	log.Error(prefix, "error", err)
+
+	if prefix != "" {
+		prefix += ": "
+	}
+
+	c.UI.Error(fmt.Sprintf("%s%s%s", ui.ERROR_PREFIX, prefix, color.RedString(" %v", err)))
+}
@@ -0,0 +1,70 @@
+package run
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func execTestGraph() *dag.AcyclicGraph {
+	// app depends on lib, which depends on base. tool is independent.
+	topoGraph := &dag.AcyclicGraph{}
+	for _, pkg := range []string{"app", "lib", "base", "tool", core.ROOT_NODE_NAME} {
+		topoGraph.Add(pkg)
+	}
+	topoGraph.Connect(dag.BasicEdge("app", "lib"))
+	topoGraph.Connect(dag.BasicEdge("lib", "base"))
+	topoGraph.Connect(dag.BasicEdge("base", core.ROOT_NODE_NAME))
+	topoGraph.Connect(dag.BasicEdge("tool", core.ROOT_NODE_NAME))
+	return topoGraph
+}
+
+func execEdges(engine *core.Scheduler) []string {
+	edges := []string{}
+	for _, e := range engine.TaskGraph.Edges() {
+		edges = append(edges, dag.VertexName(e.Source())+" -> "+dag.VertexName(e.Target()))
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+func Test_buildExecGraph(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Packages    []string
+		Topological bool
+		Expected    []string
+	}{
+		{
+			"unordered",
+			[]string{"app", "lib", "tool"},
+			false,
+			[]string{"app#exec -> ___ROOT___", "lib#exec -> ___ROOT___", "tool#exec -> ___ROOT___"},
+		},
+		{
+			"topological",
+			[]string{"app", "lib", "base", "tool"},
+			true,
+			[]string{"app#exec -> lib#exec", "base#exec -> ___ROOT___", "lib#exec -> base#exec", "tool#exec -> ___ROOT___"},
+		},
+		{
+			"topological through packages out of scope",
+			[]string{"app", "base"},
+			true,
+			[]string{"app#exec -> base#exec", "base#exec -> ___ROOT___"},
+		},
+	}
+	for _, tc := range cases {
+		engine, err := buildExecGraph(execTestGraph(), util.SetFromStrings(tc.Packages), tc.Topological)
+		if err != nil {
+			t.Fatalf("%v: buildExecGraph: %v", tc.Name, err)
+		}
+		if got := execEdges(engine); !reflect.DeepEqual(got, tc.Expected) {
+			t.Errorf("%v: got edges %v, want %v", tc.Name, got, tc.Expected)
+		}
+	}
+}
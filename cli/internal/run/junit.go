@@ -0,0 +1,218 @@
+package run
+
+import (
+	gocontext "context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// _junitMaxOutputLines is how many lines from the end of a task's log are included
+// in the report, so that chatty tasks don't produce enormous reports
+const _junitMaxOutputLines = 100
+
+// _ansiEscape matches color codes, and _invalidXML matches the remaining characters which
+// can't appear in XML documents
+var (
+	_ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	_invalidXML = regexp.MustCompile(`[\x00-\x08\x0b\x0c\x0e-\x1f]`)
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the tasks of a single package
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	Cases     []*junitTestCase `xml:"testcase"`
+	startAt   time.Time
+	duration  time.Duration
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitMessage    `xml:"failure,omitempty"`
+	Skipped    *junitMessage    `xml:"skipped,omitempty"`
+	SystemOut  *junitOutput     `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Contents string `xml:",cdata"`
+}
+
+// junitSeconds formats a duration the way JUnit reports expect
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// buildJUnitReport turns the state of a finished run into a report with one test
+// suite per package and one test case per task. Tasks without a script in their
+// package never ran, and are left out. readLog returns the output of a task.
+func buildJUnitReport(name string, duration time.Duration, states []BuildTargetState, skipped []string, hasCommand func(taskID string) bool, readLog func(state BuildTargetState) string) *junitTestSuites {
+	suites := make(map[string]*junitTestSuite)
+	report := &junitTestSuites{Name: name, Time: junitSeconds(duration)}
+	addCase := func(taskID string, startAt time.Time, d time.Duration) *junitTestCase {
+		pkg, task := util.GetPackageTaskFromId(taskID)
+		suite, ok := suites[pkg]
+		if !ok {
+			suite = &junitTestSuite{Name: pkg}
+			suites[pkg] = suite
+		}
+		if !startAt.IsZero() && (suite.startAt.IsZero() || startAt.Before(suite.startAt)) {
+			suite.startAt = startAt
+		}
+		suite.duration += d
+		suite.Tests++
+		report.Tests++
+		tc := &junitTestCase{Name: task, Classname: pkg, Time: junitSeconds(d)}
+		suite.Cases = append(suite.Cases, tc)
+		return tc
+	}
+	skip := func(tc *junitTestCase, message string) {
+		tc.Skipped = &junitMessage{Message: message}
+		suites[tc.Classname].Skipped++
+		report.Skipped++
+	}
+
+	for _, state := range states {
+		if !hasCommand(state.Label) {
+			continue
+		}
+		tc := addCase(state.Label, state.StartAt, state.Duration)
+		cached := state.Status == TargetCached
+		tc.Properties = &junitProperties{[]junitProperty{{Name: "cached", Value: fmt.Sprintf("%v", cached)}}}
+		if state.Status == TargetResumed {
+			tc.Properties.Properties = append(tc.Properties.Properties, junitProperty{Name: "resumed", Value: "true"})
+		}
+		switch state.Status {
+		case TargetBuildFailed, TargetBuildTimedOut:
+			message := "task failed"
+			if state.Err != nil {
+				message = state.Err.Error()
+			}
+			tc.Failure = &junitMessage{Message: message, Contents: readLog(state)}
+			suites[tc.Classname].Failures++
+			report.Failures++
+		case TargetBuilt, TargetCached, TargetResumed:
+			if output := readLog(state); output != "" {
+				tc.SystemOut = &junitOutput{output}
+			}
+		default:
+			skip(tc, "stopped before finishing")
+		}
+	}
+	for _, taskID := range skipped {
+		if hasCommand(taskID) {
+			skip(addCase(taskID, time.Time{}, 0), "skipped because a dependency failed")
+		}
+	}
+
+	for _, suite := range suites {
+		suite.Time = junitSeconds(suite.duration)
+		if !suite.startAt.IsZero() {
+			suite.Timestamp = suite.startAt.Format(time.RFC3339)
+		}
+		sort.Slice(suite.Cases, func(i, j int) bool {
+			return suite.Cases[i].Name < suite.Cases[j].Name
+		})
+		report.Suites = append(report.Suites, suite)
+	}
+	sort.Slice(report.Suites, func(i, j int) bool {
+		return report.Suites[i].Name < report.Suites[j].Name
+	})
+	return report
+}
+
+// trimTaskLog prepares the contents of a task's log file for a report: colors and
+// the task prefix on each line are removed, and only the last lines are kept
+func trimTaskLog(taskID string, contents string) string {
+	pkg, task := util.GetPackageTaskFromId(taskID)
+	prefix := fmt.Sprintf("%v:%v: ", pkg, task)
+	contents = _invalidXML.ReplaceAllString(_ansiEscape.ReplaceAllString(contents, ""), "")
+	lines, omitted := tailLogLines(contents, _junitMaxOutputLines)
+	var b strings.Builder
+	if omitted > 0 {
+		fmt.Fprintf(&b, "[%v earlier lines omitted]\n", omitted)
+	}
+	for _, line := range lines {
+		b.WriteString(strings.TrimPrefix(line, prefix))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// writeJUnitReport writes a JUnit XML report of the run to path
+func (r *run) writeJUnitReport(ctx gocontext.Context, path fs.AbsolutePath, g *completeGraph, ec *execContext, duration time.Duration) error {
+	hasCommand := func(taskID string) bool {
+		hasCommand := false
+		_ = g.getPackageTaskVisitor(gocontext.Background(), func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+			_, hasCommand = pt.Command()
+			return nil
+		})(taskID)
+		return hasCommand
+	}
+	readLog := func(state BuildTargetState) string {
+		if !ec.hasCurrentLog(state) {
+			return ""
+		}
+		output := ""
+		_ = g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+			contents, err := r.config.Cwd.Join(pt.RepoRelativeLogFile()).ReadFile()
+			if err == nil {
+				output = trimTaskLog(state.Label, string(contents))
+			}
+			return nil
+		})(state.Label)
+		return output
+	}
+	name := "turbo run " + strings.Join(ec.rs.Targets, " ")
+	report := buildJUnitReport(name, duration, ec.runState.TargetStates(), ec.runState.Skipped(), hasCommand, readLog)
+	contents, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to render JUnit report")
+	}
+	if err := path.EnsureDir(); err != nil {
+		return errors.Wrap(err, "failed to create directory for JUnit report")
+	}
+	if err := path.WriteFile(append([]byte(xml.Header), append(contents, '\n')...), 0644); err != nil {
+		return errors.Wrap(err, "failed to write JUnit report")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_buildJUnitReport(t *testing.T) {
+	start := time.Date(2022, 10, 12, 18, 40, 0, 0, time.UTC)
+	states := []BuildTargetState{
+		{Label: "lib#build", StartAt: start, Duration: 2 * time.Second, Status: TargetBuilt},
+		{Label: "lib#test", StartAt: start.Add(time.Second), Duration: 1500 * time.Millisecond, Status: TargetBuildFailed, Err: errors.New("running lib#test failed")},
+		{Label: "web#build", StartAt: start, Duration: 100 * time.Millisecond, Status: TargetCached},
+		{Label: "web#lint", StartAt: start, Status: TargetBuilding},
+		{Label: "web#noop", StartAt: start, Status: TargetBuilding},
+		{Label: "docs#build", StartAt: start, Status: TargetResumed},
+	}
+	hasCommand := func(taskID string) bool {
+		return taskID != "web#noop"
+	}
+	readLog := func(state BuildTargetState) string {
+		return "output of " + state.Label
+	}
+	report := buildJUnitReport("turbo run build test", 5*time.Second, states, []string{"web#test"}, hasCommand, readLog)
+
+	assert.Equal(t, report.Tests, 6)
+	assert.Equal(t, report.Failures, 1)
+	assert.Equal(t, report.Skipped, 2)
+	assert.Equal(t, report.Time, "5.000")
+	assert.Equal(t, len(report.Suites), 3)
+
+	docs := report.Suites[0]
+	assert.Equal(t, docs.Name, "docs")
+	assert.Assert(t, docs.Cases[0].Skipped == nil)
+	assert.DeepEqual(t, docs.Cases[0].Properties.Properties, []junitProperty{{Name: "cached", Value: "false"}, {Name: "resumed", Value: "true"}})
+
+	lib := report.Suites[1]
+	assert.Equal(t, lib.Name, "lib")
+	assert.Equal(t, lib.Time, "3.500")
+	assert.Equal(t, lib.Timestamp, "2022-10-12T18:40:00Z")
+	assert.Equal(t, lib.Failures, 1)
+	assert.Equal(t, lib.Cases[0].Name, "build")
+	assert.Equal(t, lib.Cases[0].SystemOut.Contents, "output of lib#build")
+	assert.Equal(t, lib.Cases[1].Name, "test")
+	assert.Equal(t, lib.Cases[1].Failure.Message, "running lib#test failed")
+	assert.Equal(t, lib.Cases[1].Failure.Contents, "output of lib#test")
+
+	web := report.Suites[2]
+	assert.Equal(t, web.Name, "web")
+	assert.Equal(t, web.Tests, 3)
+	assert.Equal(t, web.Skipped, 2)
+	assert.Equal(t, web.Cases[0].Name, "build")
+	assert.DeepEqual(t, web.Cases[0].Properties.Properties, []junitProperty{{Name: "cached", Value: "true"}})
+	assert.Equal(t, web.Cases[1].Name, "lint")
+	assert.Equal(t, web.Cases[1].Skipped.Message, "stopped before finishing")
+	assert.Equal(t, web.Cases[2].Name, "test")
+	assert.Equal(t, web.Cases[2].Skipped.Message, "skipped because a dependency failed")
+	assert.Assert(t, web.Cases[2].Properties == nil)
+}
+
+func Test_trimTaskLog(t *testing.T) {
+	log := "\x1b[2mweb:build: \x1b[0mcache hit, replaying output \x1b[2m0123\x1b[0m\n" +
+		"\x1b[2mweb:build: \x1b[0mcompiling\x07\n" +
+		"\x1b[2mweb:build: \x1b[0m\x1b[31merror\x1b[0m: oops\n"
+	assert.Equal(t, trimTaskLog("web#build", log), "compiling\nerror: oops\n")
+
+	var long strings.Builder
+	for i := 0; i < _junitMaxOutputLines+5; i++ {
+		fmt.Fprintf(&long, "web:build: line %v\n", i)
+	}
+	trimmed := trimTaskLog("web#build", long.String())
+	lines := strings.Split(strings.TrimSuffix(trimmed, "\n"), "\n")
+	assert.Equal(t, len(lines), _junitMaxOutputLines+1)
+	assert.Equal(t, lines[0], "[5 earlier lines omitted]")
+	assert.Equal(t, lines[1], "line 5")
+}
@@ -0,0 +1,192 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pyr-sh/dag"
+	"github.com/spf13/cobra"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/graphvisualizer"
+	"github.com/vercel/turborepo/cli/internal/query"
+	"github.com/vercel/turborepo/cli/internal/scope"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+)
+
+// QueryCommand is a Command implementation that answers questions about the
+// package and task graphs
+type QueryCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _queryCmdLong = `
+Query the package graph and the task graph of your monorepo.
+
+A query selects packages, such as web or @scope/ui, and tasks, such as
+web#build or //#lint. Names may use * as a wildcard. Queries combine them
+with the following functions and operators:
+
+  deps(x[, depth])     x and everything it depends on
+  rdeps(x[, depth])    x and everything that depends on it
+  allpaths(from, to)   everything on a dependency path from 'from' to 'to'
+  somepath(from, to)   a single dependency path from 'from' to 'to', in order
+  filter(pattern, x)   members of x whose names match a regular expression
+  packages(x)          x, with tasks replaced by their packages
+  tasks(x[, name])     x, with packages replaced by their tasks (or only 'name')
+  owner(file)          the package containing a repo-relative file
+  x + y, x union y     members of either x or y
+  x - y, x except y    members of x which are not in y
+  x ^ y, x intersect y members of both x and y
+
+The task graph contains every task in the pipeline, in every package. Quote
+arguments which contain spaces or operators, e.g. filter("^web", deps(web#build)).
+`
+
+const (
+	_queryOutputText = "text"
+	_queryOutputJSON = "json"
+)
+
+func getQueryCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:                   "turbo query <expression> [<flags>]",
+		Short:                 "Query the package and task graphs of your monorepo",
+		Long:                  _queryCmdLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("a query expression must be specified")
+			}
+			if outputFormat != _queryOutputText && outputFormat != _queryOutputJSON {
+				return fmt.Errorf("invalid value for --output: %q, expected %q or %q", outputFormat, _queryOutputText, _queryOutputJSON)
+			}
+			q, err := query.Parse(strings.Join(args, " "))
+			if err != nil {
+				return errors.Wrap(err, "invalid query")
+			}
+			r := &run{
+				opts:   getDefaultOptions(config),
+				config: config,
+				ui:     output,
+			}
+			g, rs, _, err := r.prepare(nil)
+			if err != nil {
+				return err
+			}
+			universe, err := g.queryUniverse(rs)
+			if err != nil {
+				return err
+			}
+			result, err := q.Eval(universe)
+			if err != nil {
+				return err
+			}
+			if outputFormat == _queryOutputJSON {
+				nodes := []graphvisualizer.Node{}
+				for _, id := range result {
+					if universe.Tasks.HasVertex(id) {
+						nodes = append(nodes, g.describeTask(id))
+					} else {
+						nodes = append(nodes, g.describePackage(id))
+					}
+				}
+				bytes, err := json.MarshalIndent(nodes, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to render JSON")
+				}
+				output.Output(string(bytes))
+				return nil
+			}
+			for _, id := range result {
+				output.Output(id)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&outputFormat, "output", _queryOutputText, `How to print results: "text" for one name per line, or "json"`)
+	noopPersistentOptsDuringMigration(cmd.Flags())
+	return cmd
+}
+
+// queryUniverse builds the graphs that queries run against: the whole package
+// graph, and the task graph for every task in the pipeline
+func (g *completeGraph) queryUniverse(rs *runSpec) (*query.Universe, error) {
+	if _, ok := g.PackageInfos[util.RootPkgName]; ok {
+		rs.FilteredPkgs.Add(util.RootPkgName)
+	}
+	engine, err := buildTaskGraph(&g.TopologicalGraph, g.Pipeline, rs)
+	if err != nil {
+		return nil, errors.Wrap(err, "error preparing task graph")
+	}
+	packages := &dag.AcyclicGraph{}
+	for _, v := range g.TopologicalGraph.Vertices() {
+		packages.Add(v)
+	}
+	for _, e := range g.TopologicalGraph.Edges() {
+		packages.Connect(e)
+	}
+	// The root package only has a vertex if it depends on workspace packages
+	if _, ok := g.PackageInfos[util.RootPkgName]; ok {
+		packages.Add(util.RootPkgName)
+	}
+	return &query.Universe{
+		Packages: packages,
+		Tasks:    engine.TaskGraph,
+		Owner: func(file string) (string, error) {
+			changed, err := scope.ChangedPackages(&scope.Opts{}, []string{filepath.Clean(filepath.FromSlash(file))}, g.PackageInfos)
+			if err != nil {
+				return "", err
+			}
+			for pkg := range changed {
+				return pkg.(string), nil
+			}
+			return util.RootPkgName, nil
+		},
+	}, nil
+}
+
+// Synopsis of query command
+func (c *QueryCommand) Synopsis() string {
+	cmd := getQueryCmd(c.Config, c.UI)
+	return cmd.Short
+}
+
+// Help returns information about the `query` command
+func (c *QueryCommand) Help() string {
+	cmd := getQueryCmd(c.Config, c.UI)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run evaluates a query against the monorepo's package and task graphs
+func (c *QueryCommand) Run(args []string) int {
+	cmd := getQueryCmd(c.Config, c.UI)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.logError(c.Config.Logger, "", err)
+		return 1
+	}
+	return 0
+}
+
+// logError logs an error and outputs it to the UI.
+func (c *QueryCommand) logError(log hclog.Logger, prefix string, err error) {
+	log.Error(prefix, "error", err)
+
+	if prefix != "" {
+		prefix += ": "
+	}
+
+	c.UI.Error(fmt.Sprintf("%s%s%s", ui.ERROR_PREFIX, prefix, color.RedString(" %v", err)))
+}
@@ -0,0 +1,75 @@
+package run
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/colorcache"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// tailLogLines returns up to the last n lines of a task's log file, along with how
+// many earlier lines were left out. The line that every log file starts with, which
+// only matters when the log is replayed for a cache hit, is dropped.
+func tailLogLines(contents string, n int) ([]string, int) {
+	contents = strings.TrimRight(contents, "\n")
+	if contents == "" {
+		return []string{}, 0
+	}
+	lines := strings.Split(contents, "\n")
+	if strings.Contains(lines[0], "cache hit, replaying output") {
+		lines = lines[1:]
+	}
+	omitted := 0
+	if len(lines) > n {
+		omitted = len(lines) - n
+		lines = lines[omitted:]
+	}
+	return lines, omitted
+}
+
+// printFailureRecap replays the end of the log of each failed task, so that errors
+// don't get lost among the output of the other tasks in a large run
+func (r *run) printFailureRecap(ctx gocontext.Context, g *completeGraph, ec *execContext, colorCache *colorcache.ColorCache, lines int) {
+	var failed []BuildTargetState
+	for _, state := range ec.runState.TargetStates() {
+		if state.Status == TargetBuildFailed || state.Status == TargetBuildTimedOut {
+			failed = append(failed, state)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+	r.ui.Output("")
+	r.ui.Output(util.Sprintf("${BOLD_RED}Failed tasks (%v)${RESET}", len(failed)))
+	for _, state := range failed {
+		_ = g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+			prefix := colorCache.PrefixColor(pt.PackageName)("%s: ", pt.OutputPrefix())
+			r.ui.Output("")
+			if state.Err != nil {
+				r.ui.Output(prefix + ui.ERROR_PREFIX + " " + state.Err.Error())
+			}
+			if !ec.hasCurrentLog(state) {
+				r.ui.Output(prefix + ui.Dim("no log file to replay"))
+				return nil
+			}
+			contents, err := r.config.Cwd.Join(pt.RepoRelativeLogFile()).ReadFile()
+			if err != nil {
+				r.ui.Output(prefix + ui.Dim("no log file to replay"))
+				return nil
+			}
+			logLines, omitted := tailLogLines(string(contents), lines)
+			if omitted > 0 {
+				r.ui.Output(prefix + ui.Dim(fmt.Sprintf("[%v earlier lines omitted, see %v]", omitted, pt.RepoRelativeLogFile())))
+			}
+			// Each line in the log file already starts with the task's colored prefix
+			for _, line := range logLines {
+				r.ui.Output(line)
+			}
+			return nil
+		})(state.Label)
+	}
+}
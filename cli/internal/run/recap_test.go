@@ -0,0 +1,37 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/util"
+	"gotest.tools/v3/assert"
+)
+
+func Test_tailLogLines(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Contents string
+		N        int
+		Lines    []string
+		Omitted  int
+	}{
+		{"empty", "", 5, []string{}, 0},
+		{"short", "lib:build: one\nlib:build: two\n", 5, []string{"lib:build: one", "lib:build: two"}, 0},
+		{"replay line is dropped", "lib:build: cache hit, replaying output 0123\nlib:build: one\n", 5, []string{"lib:build: one"}, 0},
+		{"long", "a\nb\nc\nd\ne\n", 2, []string{"d", "e"}, 3},
+	}
+	for _, tc := range cases {
+		lines, omitted := tailLogLines(tc.Contents, tc.N)
+		assert.DeepEqual(t, lines, tc.Lines)
+		assert.Equal(t, omitted, tc.Omitted, tc.Name)
+	}
+}
+
+func Test_hasCurrentLog(t *testing.T) {
+	ec := &execContext{logsWritten: make(util.Set)}
+	ec.logWritten("lib#build")
+
+	assert.Assert(t, ec.hasCurrentLog(BuildTargetState{Label: "lib#build", Status: TargetBuildFailed}), "log written by the task")
+	assert.Assert(t, !ec.hasCurrentLog(BuildTargetState{Label: "web#build", Status: TargetBuildFailed}), "log left over from an earlier run")
+	assert.Assert(t, ec.hasCurrentLog(BuildTargetState{Label: "web#build", Status: TargetCached}), "log restored from the cache")
+}
@@ -0,0 +1,96 @@
+package run
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+// _lastRunFile records the tasks which succeeded in the most recent run, for --resume.
+// Unlike the task duration history, it is kept in the repository's .turbo directory
+// rather than with the cache, since resuming relies on the outputs of the previous
+// run still being on disk.
+const _lastRunFile = "last-run.json"
+
+// _lastRunLock is the name of the lock which turbo processes hold while they update
+// the record, and _lastRunLockMaxWait is how long they wait for it. Updates are
+// quick, so a lock held for longer than that belongs to a process which is stuck.
+const (
+	_lastRunLock        = "last-run"
+	_lastRunLockMaxWait = 10 * time.Second
+)
+
+// lastRun is the record of the tasks which succeeded in the most recent run
+type lastRun struct {
+	// Succeeded maps the id of each task which succeeded to the hash it ran with
+	Succeeded map[string]string `json:"succeeded"`
+}
+
+func lastRunPath(repoRoot fs.AbsolutePath) fs.AbsolutePath {
+	return repoRoot.Join(".turbo", _lastRunFile)
+}
+
+// loadLastRun reads the record of the previous run. A missing or unreadable record
+// is treated as empty, so that every task runs.
+func loadLastRun(repoRoot fs.AbsolutePath) *lastRun {
+	lr := &lastRun{Succeeded: make(map[string]string)}
+	contents, err := lastRunPath(repoRoot).ReadFile()
+	if err == nil {
+		if err := json.Unmarshal(contents, lr); err != nil || lr.Succeeded == nil {
+			lr.Succeeded = make(map[string]string)
+		}
+	}
+	return lr
+}
+
+// succeeded reports whether the given task succeeded with the same hash in the previous run
+func (lr *lastRun) succeeded(taskID string, hash string) bool {
+	previousHash, ok := lr.Succeeded[taskID]
+	return ok && hash != "" && previousHash == hash
+}
+
+// save replaces the saved record with this one. The record is written to a temporary
+// file which then replaces the old one, so that a crash can't leave it truncated.
+func (lr *lastRun) save(repoRoot fs.AbsolutePath) error {
+	contents, err := json.Marshal(lr)
+	if err != nil {
+		return err
+	}
+	path := lastRunPath(repoRoot)
+	if err := path.EnsureDir(); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir().ToString(), _lastRunFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := fs.AbsolutePathFromUpstream(tmp.Name())
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = tmpPath.Rename(path)
+	}
+	if err != nil {
+		_ = tmpPath.Remove()
+	}
+	return err
+}
+
+// recordLastRun applies update to the saved record. The lock keeps turbo processes
+// which finish at the same time from losing each other's updates.
+func recordLastRun(repoRoot fs.AbsolutePath, locks *taskLocks, update func(lr *lastRun)) error {
+	// The run may have been interrupted, which shouldn't stop its results being recorded
+	release, err := locks.acquire(gocontext.Background(), _lastRunLock, _lastRunLockMaxWait, func(pid int) {})
+	if err != nil {
+		return err
+	}
+	defer release()
+	lr := loadLastRun(repoRoot)
+	update(lr)
+	return lr.save(repoRoot)
+}
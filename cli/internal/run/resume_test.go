@@ -0,0 +1,104 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+func Test_lastRun(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+
+	previous := loadLastRun(repoRoot)
+	assert.Assert(t, !previous.succeeded("lib#build", "abc"), "no previous run")
+
+	lr := &lastRun{Succeeded: map[string]string{"lib#build": "abc"}}
+	assert.NilError(t, lr.save(repoRoot), "save")
+
+	previous = loadLastRun(repoRoot)
+	assert.Assert(t, previous.succeeded("lib#build", "abc"))
+	assert.Assert(t, !previous.succeeded("lib#build", "def"), "hash changed")
+	assert.Assert(t, !previous.succeeded("lib#build", ""), "hashing failed")
+	assert.Assert(t, !previous.succeeded("app#build", "abc"), "task didn't succeed")
+}
+
+func Test_lastRunCorrupt(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	path := lastRunPath(repoRoot)
+	assert.NilError(t, path.EnsureDir(), "EnsureDir")
+	assert.NilError(t, path.WriteFile([]byte("not json"), 0644), "WriteFile")
+
+	previous := loadLastRun(repoRoot)
+	assert.DeepEqual(t, previous.Succeeded, map[string]string{})
+}
+
+func Test_runSummaryLastRun(t *testing.T) {
+	summary := &runSummary{
+		Tasks: []*taskSummary{
+			{TaskID: "a#build", Hash: "a", Status: _taskStatusBuilt},
+			{TaskID: "b#build", Hash: "b", Status: _taskStatusCached},
+			{TaskID: "c#build", Hash: "c", Status: _taskStatusResumed},
+			{TaskID: "d#build", Hash: "d", Status: _taskStatusFailed},
+			{TaskID: "e#build", Hash: "e", Status: _taskStatusStopped},
+			{TaskID: "f#build", Hash: "", Status: _taskStatusBuilt},
+			{TaskID: "g#build", Hash: "", Status: _taskStatusSkipped},
+		},
+	}
+	lr := &lastRun{Succeeded: map[string]string{
+		"a#build":     "old",
+		"d#build":     "old",
+		"g#build":     "g",
+		"other#build": "other",
+	}}
+	summary.updateLastRun(lr)
+	assert.DeepEqual(t, lr.Succeeded, map[string]string{
+		"a#build": "a",
+		"b#build": "b",
+		"c#build": "c",
+		// Tasks which didn't start in this run keep their record
+		"g#build":     "g",
+		"other#build": "other",
+	})
+}
+
+func Test_recordLastRun(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	locks := newTaskLocks(repoRoot, hclog.NewNullLogger())
+	assert.NilError(t, (&lastRun{Succeeded: map[string]string{"lib#build": "abc"}}).save(repoRoot), "save")
+
+	// Another process is updating the record, so this one waits for it to finish
+	lockFile := repoRoot.Join(".turbo", "locks", _lastRunLock+".lock")
+	assert.NilError(t, lockFile.EnsureDir(), "EnsureDir")
+	assert.NilError(t, lockFile.WriteFile([]byte(fmt.Sprintf("%d\n", os.Getppid())), 0644), "WriteFile")
+	otherSaved := make(chan error, 1)
+	go func() {
+		time.Sleep(2 * _taskLockPollInterval)
+		otherSaved <- (&lastRun{Succeeded: map[string]string{"lib#build": "abc", "web#build": "def"}}).save(repoRoot)
+		_ = lockFile.Remove()
+	}()
+	err := recordLastRun(repoRoot, locks, func(lr *lastRun) {
+		lr.Succeeded["app#build"] = "ghi"
+	})
+	assert.NilError(t, err, "recordLastRun")
+	assert.NilError(t, <-otherSaved, "save")
+	assert.DeepEqual(t, loadLastRun(repoRoot).Succeeded, map[string]string{
+		"lib#build": "abc",
+		"web#build": "def",
+		"app#build": "ghi",
+	})
+	assert.Assert(t, !lockFile.FileExists())
+
+	// Only the record is left behind, not the temporary files it was written through
+	entries, err := os.ReadDir(lastRunPath(repoRoot).Dir().ToString())
+	assert.NilError(t, err, "ReadDir")
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.DeepEqual(t, names, []string{_lastRunFile, "locks"})
+}
@@ -4,12 +4,14 @@ import (
 	gocontext "context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
@@ -20,14 +22,17 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/client"
 	"github.com/vercel/turborepo/cli/internal/colorcache"
 	"github.com/vercel/turborepo/cli/internal/config"
 	"github.com/vercel/turborepo/cli/internal/context"
 	"github.com/vercel/turborepo/cli/internal/core"
 	"github.com/vercel/turborepo/cli/internal/daemon"
 	"github.com/vercel/turborepo/cli/internal/daemonclient"
+	"github.com/vercel/turborepo/cli/internal/env"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"github.com/vercel/turborepo/cli/internal/graphvisualizer"
+	"github.com/vercel/turborepo/cli/internal/inference"
 	"github.com/vercel/turborepo/cli/internal/logstreamer"
 	"github.com/vercel/turborepo/cli/internal/nodes"
 	"github.com/vercel/turborepo/cli/internal/packagemanager"
@@ -42,6 +47,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/hashicorp/go-hclog"
+	"github.com/kballard/go-shellquote"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
 )
@@ -60,7 +66,9 @@ type completeGraph struct {
 	Pipeline         fs.Pipeline
 	PackageInfos     map[interface{}]*fs.PackageJSON
 	GlobalHash       string
+	GlobalHashInputs *context.GlobalHashInputs
 	RootNode         string
+	Frameworks       []inference.Framework
 }
 
 // runSpec contains the run-specific configuration elements that come from a particular
@@ -71,10 +79,22 @@ type runSpec struct {
 	Opts         *Opts
 }
 
-func (rs *runSpec) ArgsForTask(task string) []string {
+// ArgsForTask returns the arguments to pass to a task's script: the task's arguments
+// from --args, or from turbo.json if none were given, then the arguments of its matrix
+// variant, if any, followed by the pass-through arguments if the task was one of the
+// targets. Targets which name a task with a matrix apply to each variant.
+func (rs *runSpec) ArgsForTask(pt *nodes.PackageTask) []string {
 	passThroughArgs := make([]string, 0, len(rs.Opts.runOpts.passThroughArgs))
+	if taskArgs, ok := rs.Opts.runOpts.argsForTask(pt); ok {
+		passThroughArgs = append(passThroughArgs, taskArgs...)
+	} else {
+		passThroughArgs = append(passThroughArgs, pt.TaskDefinition.Args...)
+	}
+	if pt.TaskDefinition.IsVariant() {
+		passThroughArgs = append(passThroughArgs, pt.TaskDefinition.Variant.Args...)
+	}
 	for _, target := range rs.Targets {
-		if target == task {
+		if target == pt.Task || target == pt.Script() {
 			passThroughArgs = append(passThroughArgs, rs.Opts.runOpts.passThroughArgs...)
 		}
 	}
@@ -108,6 +128,9 @@ func getCmd(config *config.Config, ui cli.Ui, signalWatcher *signals.Watcher) *c
 			if len(tasks) == 0 {
 				return errors.New("at least one task must be specified")
 			}
+			if err := opts.runOpts.validate(); err != nil {
+				return err
+			}
 			opts.runOpts.passThroughArgs = passThroughArgs
 			run := configureRun(config, ui, opts, signalWatcher)
 			ctx := cmd.Context()
@@ -204,16 +227,6 @@ type run struct {
 
 func (r *run) run(ctx gocontext.Context, targets []string) error {
 	startAt := time.Now()
-	turboJSON, err := fs.ReadTurboConfig(r.config.Cwd, r.config.RootPackageJSON)
-	if err != nil {
-		return err
-	}
-	// TODO: these values come from a config file, hopefully viper can help us merge these
-	r.opts.cacheOpts.RemoteCacheOpts = turboJSON.RemoteCacheOptions
-	pkgDepGraph, err := context.New(context.WithGraph(r.config, turboJSON, r.opts.cacheOpts.Dir))
-	if err != nil {
-		return err
-	}
 	// This technically could be one flag, but we plan on removing
 	// the daemon opt-in flag at some point once it stabilizes
 	if r.opts.runOpts.daemonOptIn && !r.opts.runOpts.noDaemon {
@@ -227,14 +240,41 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 			r.opts.runcacheOpts.OutputWatcher = daemonClient
 		}
 	}
+	g, rs, packageManager, err := r.prepare(targets)
+	if err != nil {
+		return err
+	}
+	return r.runOperation(ctx, g, rs, packageManager, startAt)
+}
+
+// prepare reads the repository's configuration and package graph, and resolves
+// which packages the given targets should run in
+func (r *run) prepare(targets []string) (*completeGraph, *runSpec, *packagemanager.PackageManager, error) {
+	turboJSON, err := fs.ReadTurboConfig(r.config.Cwd, r.config.RootPackageJSON)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// TODO: these values come from a config file, hopefully viper can help us merge these
+	r.opts.cacheOpts.RemoteCacheOpts = turboJSON.RemoteCacheOptions
+	pkgDepGraph, err := context.New(context.WithGraph(r.config, turboJSON, r.opts.cacheOpts.Dir))
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	if err := util.ValidateGraph(&pkgDepGraph.TopologicalGraph); err != nil {
-		return errors.Wrap(err, "Invalid package dependency graph")
+		return nil, nil, nil, errors.Wrap(err, "Invalid package dependency graph")
 	}
 
 	pipeline := turboJSON.Pipeline
 	if err := validateTasks(pipeline, targets); err != nil {
-		return err
+		return nil, nil, nil, err
+	}
+	if err := validateTaskArgs(pipeline, r.opts.runOpts.taskArgs); err != nil {
+		return nil, nil, nil, err
+	}
+	frameworks, err := inference.Frameworks(turboJSON)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid frameworks configuration in turbo.json")
 	}
 
 	scmInstance, err := scm.FromInRepo(r.config.Cwd.ToStringDuringMigration())
@@ -242,12 +282,12 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 		if errors.Is(err, scm.ErrFallback) {
 			r.logWarning("", err)
 		} else {
-			return errors.Wrap(err, "failed to create SCM")
+			return nil, nil, nil, errors.Wrap(err, "failed to create SCM")
 		}
 	}
 	filteredPkgs, isAllPackages, err := scope.ResolvePackages(&r.opts.scopeOpts, r.config.Cwd.ToStringDuringMigration(), scmInstance, pkgDepGraph, r.ui, r.config.Logger)
 	if err != nil {
-		return errors.Wrap(err, "failed to resolve packages to run")
+		return nil, nil, nil, errors.Wrap(err, "failed to resolve packages to run")
 	}
 	if isAllPackages {
 		// if there is a root task for any of our targets, we need to add it
@@ -269,15 +309,16 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 		Pipeline:         pipeline,
 		PackageInfos:     pkgDepGraph.PackageInfos,
 		GlobalHash:       pkgDepGraph.GlobalHash,
+		GlobalHashInputs: pkgDepGraph.GlobalHashInputs,
 		RootNode:         pkgDepGraph.RootNode,
+		Frameworks:       frameworks,
 	}
 	rs := &runSpec{
 		Targets:      targets,
 		FilteredPkgs: filteredPkgs,
 		Opts:         r.opts,
 	}
-	packageManager := pkgDepGraph.PackageManager
-	return r.runOperation(ctx, g, rs, packageManager, startAt)
+	return g, rs, pkgDepGraph.PackageManager, nil
 }
 
 func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec, packageManager *packagemanager.PackageManager, startAt time.Time) error {
@@ -290,12 +331,18 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 	if err != nil {
 		return errors.Wrap(err, "error preparing engine")
 	}
-	hashTracker := taskhash.NewTracker(g.RootNode, g.GlobalHash, g.Pipeline, g.PackageInfos)
+	hashTracker := taskhash.NewTracker(g.RootNode, g.GlobalHash, g.Pipeline, g.PackageInfos, g.Frameworks)
 	err = hashTracker.CalculateFileHashes(engine.TaskGraph.Vertices(), rs.Opts.runOpts.concurrency, r.config.Cwd)
 	if err != nil {
 		return errors.Wrap(err, "error hashing package files")
 	}
 
+	// Capture the package graph before --parallel removes its edges
+	var packageGraph *dag.AcyclicGraph
+	if rs.Opts.runOpts.graphType == _packageGraphType {
+		packageGraph = g.packageGraph(rs.FilteredPkgs)
+	}
+
 	// If we are running in parallel, then we remove all the edges in the graph
 	// except for the root. Rebuild the task graph for backwards compatibility.
 	// We still use dependencies specified by the pipeline configuration.
@@ -310,9 +357,17 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 			return errors.Wrap(err, "error preparing engine")
 		}
 	}
+	if err := validatePersistentConcurrency(engine, rs.Opts.runOpts); err != nil {
+		return err
+	}
 
 	if rs.Opts.runOpts.graphFile != "" || rs.Opts.runOpts.graphDot {
-		visualizer := graphvisualizer.New(r.config, r.ui, engine.TaskGraph)
+		var visualizer *graphvisualizer.GraphVisualizer
+		if rs.Opts.runOpts.graphType == _packageGraphType {
+			visualizer = graphvisualizer.New(r.config, r.ui, _packageGraphType, packageGraph, g.describePackage)
+		} else {
+			visualizer = graphvisualizer.New(r.config, r.ui, _taskGraphType, engine.TaskGraph, g.describeTask)
+		}
 
 		if rs.Opts.runOpts.graphDot {
 			visualizer.RenderDotGraph()
@@ -327,15 +382,29 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		if err != nil {
 			return err
 		}
+		// With --force, nothing is restored from the cache, so there is nothing to check
+		var turboCache cache.Cache
+		if !rs.Opts.runcacheOpts.SkipReads {
+			analyticsClient := analytics.NewClient(ctx, analytics.NullSink, r.config.Logger.Named("analytics"))
+			defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+			turboCache, err = r.newCache(rs, r.config.NewClient(), analyticsClient)
+			if err != nil {
+				return err
+			}
+			defer turboCache.Shutdown()
+		}
+		cacheSummary := r.checkCache(turboCache, tasksRun)
 		packagesInScope := rs.FilteredPkgs.UnsafeListOfStrings()
 		sort.Strings(packagesInScope)
 		if rs.Opts.runOpts.dryRunJSON {
 			dryRun := &struct {
-				Packages []string     `json:"packages"`
-				Tasks    []hashedTask `json:"tasks"`
+				Packages     []string            `json:"packages"`
+				Tasks        []hashedTask        `json:"tasks"`
+				CacheSummary *dryRunCacheSummary `json:"cacheSummary"`
 			}{
-				Packages: packagesInScope,
-				Tasks:    tasksRun,
+				Packages:     packagesInScope,
+				Tasks:        tasksRun,
+				CacheSummary: cacheSummary,
 			}
 			bytes, err := json.MarshalIndent(dryRun, "", "  ")
 			if err != nil {
@@ -367,8 +436,20 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Log File\t=\t%s\t${RESET}", task.LogFile))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Dependencies\t=\t%s\t${RESET}", strings.Join(task.Dependencies, ", ")))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Dependendents\t=\t%s\t${RESET}", strings.Join(task.Dependents, ", ")))
+				fmt.Fprintln(w, util.Sprintf("  ${GREY}Framework\t=\t%s\t${RESET}", task.Framework))
+				if task.Cache != nil {
+					fmt.Fprintln(w, util.Sprintf("  ${GREY}Cached (Local)\t=\t%v\t${RESET}", task.Cache.Local))
+					fmt.Fprintln(w, util.Sprintf("  ${GREY}Cached (Remote)\t=\t%v\t${RESET}", task.Cache.Remote))
+				}
 				w.Flush()
 			}
+
+			r.ui.Output("")
+			r.ui.Info(util.Sprintf("${CYAN}${BOLD}Expected Cache Status${RESET}"))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+			fmt.Fprintln(w, util.Sprintf("  ${GREY}Hits\t=\t%v\t${RESET}", cacheSummary.Hits))
+			fmt.Fprintln(w, util.Sprintf("  ${GREY}Misses\t=\t%v\t${RESET}", cacheSummary.Misses))
+			w.Flush()
 		}
 	} else {
 		packagesInScope := rs.FilteredPkgs.UnsafeListOfStrings()
@@ -399,10 +480,20 @@ func buildTaskGraph(topoGraph *dag.AcyclicGraph, pipeline fs.Pipeline, rs *runSp
 		for _, dependency := range taskDefinition.TopologicalDependencies {
 			topoDeps.Add(dependency)
 		}
+		var variants []string
+		for _, variant := range taskDefinition.Matrix {
+			variants = append(variants, variant.TaskName())
+		}
 		engine.AddTask(&core.Task{
-			Name:     taskName,
-			TopoDeps: topoDeps,
-			Deps:     deps,
+			Name:       taskName,
+			TopoDeps:   topoDeps,
+			Deps:       deps,
+			Persistent: taskDefinition.Persistent,
+			Resources: core.Resources{
+				CPU:      taskDefinition.Resources.CPU,
+				MemoryMB: taskDefinition.Resources.MemoryMB,
+			},
+			Variants: variants,
 		})
 	}
 
@@ -421,6 +512,30 @@ func buildTaskGraph(topoGraph *dag.AcyclicGraph, pipeline fs.Pipeline, rs *runSp
 	return engine, nil
 }
 
+// validatePersistentConcurrency ensures that persistent tasks, which hold onto their
+// slot until turbo exits, leave room for the rest of the graph to make progress.
+func validatePersistentConcurrency(engine *core.Scheduler, opts runOpts) error {
+	if opts.parallel {
+		return nil
+	}
+	persistent := engine.PersistentTasks()
+	// Persistent tasks may declare more than 1 cpu, and memory, which they hold for the whole run
+	persistentCPU := 0
+	persistentMemoryMB := 0
+	for _, taskID := range persistent {
+		resources := engine.TaskResources(taskID)
+		persistentCPU += resources.CPU
+		persistentMemoryMB += resources.MemoryMB
+	}
+	if len(persistent) > 0 && persistentCPU >= opts.concurrency {
+		return fmt.Errorf("You have %v persistent tasks using %v cpu but `turbo` is configured for concurrency of %v. Set --concurrency to at least %v", len(persistent), persistentCPU, opts.concurrency, persistentCPU+1)
+	}
+	if opts.memoryBudget > 0 && persistentMemoryMB > 0 && persistentMemoryMB >= opts.memoryBudget {
+		return fmt.Errorf("You have %v persistent tasks using %vMB of memory but `turbo` is configured for a memory budget of %vMB. Set --memory-budget to at least %v", len(persistent), persistentMemoryMB, opts.memoryBudget, persistentMemoryMB+1)
+	}
+	return nil
+}
+
 // Opts holds the current run operations configuration
 type Opts struct {
 	runOpts      runOpts
@@ -435,23 +550,51 @@ type runOpts struct {
 	dotGraph string
 	// Force execution to be serially one-at-a-time
 	concurrency int
+	// The total memory, in MB, that executing tasks may declare. 0 is unlimited.
+	memoryBudget int
 	// Whether to execute in parallel (defaults to false)
 	parallel bool
 	// Whether to emit a perf profile
 	profile string
 	// If true, continue task executions even if a task fails.
 	continueOnError bool
-	passThroughArgs []string
+	// If true, the tasks which were skipped because they depend on a failed task are
+	// reported. Only meaningful with continueOnError.
+	skipFailedDependents bool
+	passThroughArgs      []string
+	// Arguments for specific tasks from --args, keyed by task name, task@variant,
+	// or package#task
+	taskArgs map[string][]string
 	// Restrict execution to only the listed task names. Default false
 	only bool
 	// Dry run flags
 	dryRun     bool
 	dryRunJSON bool
 	// Graph flags
-	graphDot    bool
-	graphFile   string
+	graphDot  bool
+	graphFile string
+	// Which graph --graph renders, "task" or "package"
+	graphType   string
 	noDaemon    bool
 	daemonOptIn bool
+	// Hash dependencies by the contents of their outputs, rather than by their task hashes
+	earlyCutoff bool
+	// If set, overrides the number of retries configured for every task
+	retries *int
+	// Timeout for tasks which don't configure their own. 0 is unlimited.
+	taskTimeout time.Duration
+	// Write a summary of the run to .turbo/runs
+	summarize bool
+	// If set, the path to write a JUnit XML report of task results to
+	reportJUnit string
+	// How many lines of each failed task's log to replay at the end of the run. 0 disables the recap.
+	failureRecapLines int
+	// Skip tasks which succeeded with the same hash in the previous run
+	resume bool
+	// Warn about files which tasks write outside of their declared outputs
+	checkOutputs bool
+	// Run scripts with the system's shell instead of through the package manager
+	directScripts bool
 }
 
 var (
@@ -459,15 +602,57 @@ var (
 You can load the file up in chrome://tracing to see
 which parts of your build were slow.`
 	_continueHelp = `Continue execution even if a task exits with an error
-or non-zero exit code. The default behavior is to bail`
+or non-zero exit code. The default behavior is to bail.
+Tasks which depend on a failed task don't run. Use
+--continue=dependencies-successful to also list them in
+the summary at the end of the run.`
 	_dryRunHelp = `List the packages in scope and the tasks that would be run,
 but don't actually run them. Passing --dry=json or
 --dry-run=json will render the output in JSON format.`
-	_graphHelp = `Generate a graph of the task execution and output to a file when a filename is specified (.svg, .png, .jpg, .pdf, .json, .html).
+	_graphHelp = `Generate a graph of the task execution and output to a file when a filename is specified (.svg, .png, .jpg, .pdf, .json, .mmd, .dot, .html).
 Outputs dot graph to stdout when if no filename is provided`
-	_concurrencyHelp = `Limit the concurrency of task execution. Use 1 for serial (i.e. one-at-a-time) execution.`
-	_parallelHelp    = `Execute all tasks in parallel.`
-	_onlyHelp        = `Run only the specified tasks, not their dependencies.`
+	_graphTypeHelp = `Which graph --graph renders: "task" (the default) for the tasks
+that would run, or "package" for the packages in scope and their
+dependencies.`
+	_concurrencyHelp = `Limit the concurrency of task execution. Use 1 for serial (i.e. one-at-a-time) execution.
+Tasks which declare "resources.cpu" in turbo.json use that many slots.`
+	_memoryBudgetHelp = `Limit the total memory, in MB, that running tasks declare
+in "resources.memoryMB" in turbo.json. 0 means unlimited.`
+	_parallelHelp = `Execute all tasks in parallel.`
+	_onlyHelp     = `Run only the specified tasks, not their dependencies.`
+	_retriesHelp  = `Retry failed tasks up to this many times, overriding
+"retries" in turbo.json.`
+	_taskTimeoutHelp = `Stop tasks which run for longer than this duration, e.g. "10m",
+unless they configure "timeout" in turbo.json. Persistent tasks
+are not affected. 0 means no timeout.`
+	_earlyCutoffHelp = `Hash the outputs of each task's dependencies instead of their
+inputs, so that changes which don't affect a dependency's outputs
+don't invalidate the tasks that depend on it. Only use this if your
+tasks consume their dependencies' outputs and not their sources.
+Hashes shown by --dry-run don't reflect this mode, since outputs
+aren't known until tasks execute.`
+	_summarizeHelp = `Write a JSON summary of the run, including each task's
+hash, cache status, timing and exit code, to .turbo/runs.`
+	_reportJUnitHelp = `Write a JUnit XML report of task results to the given file,
+with a test case for each task.`
+	_resumeHelp = `Skip tasks which succeeded with the same hash in the
+previous run, even if their outputs weren't cached, so that
+only failed and never-started tasks run again.`
+	_checkOutputsHelp = `Warn about files which tasks create or modify outside of
+their declared outputs, since they won't be restored from the
+cache, and about outputs which match no files. Each package is
+scanned before and after its tasks run, and its tasks run one at
+a time, which slows down runs.`
+	_directScriptsHelp = `Run scripts from package.json with the system's shell instead
+of through the package manager, which saves the package manager's
+startup time for every task. node_modules/.bin is added to PATH, and
+pre and post scripts run if the package manager would run them.`
+	_argsHelp = `Pass arguments to a single task's script, e.g. --args test="--coverage".
+The task may be a task name, task@variant or package#task. Replaces the
+"args" configured in turbo.json. Can be repeated.`
+	_failureRecapLinesHelp = `Replay this many lines from the end of each failed task's
+log after the run, so that errors aren't lost among other
+output. Defaults to 0, which disables the recap.`
 )
 
 func addRunOpts(opts *runOpts, flags *pflag.FlagSet, aliases map[string]string) {
@@ -479,10 +664,35 @@ func addRunOpts(opts *runOpts, flags *pflag.FlagSet, aliases map[string]string)
 			Value: &opts.concurrency,
 		},
 	})
+	flags.IntVar(&opts.memoryBudget, "memory-budget", 0, _memoryBudgetHelp)
 	flags.BoolVar(&opts.parallel, "parallel", false, _parallelHelp)
 	flags.StringVar(&opts.profile, "profile", "", _profileHelp)
-	flags.BoolVar(&opts.continueOnError, "continue", false, _continueHelp)
+	flags.AddFlag(&pflag.Flag{
+		Name:        "continue",
+		Usage:       _continueHelp,
+		DefValue:    "false",
+		NoOptDefVal: "true",
+		Value:       &continueValue{opts: opts},
+	})
 	flags.BoolVar(&opts.only, "only", false, _onlyHelp)
+	flags.BoolVar(&opts.earlyCutoff, "early-cutoff", false, _earlyCutoffHelp)
+	flags.AddFlag(&pflag.Flag{
+		Name:  "retries",
+		Usage: _retriesHelp,
+		Value: &retriesValue{opts: opts},
+	})
+	flags.DurationVar(&opts.taskTimeout, "task-timeout", 0, _taskTimeoutHelp)
+	flags.BoolVar(&opts.summarize, "summarize", false, _summarizeHelp)
+	flags.StringVar(&opts.reportJUnit, "report-junit", "", _reportJUnitHelp)
+	flags.BoolVar(&opts.resume, "resume", false, _resumeHelp)
+	flags.BoolVar(&opts.checkOutputs, "check-outputs", false, _checkOutputsHelp)
+	flags.BoolVar(&opts.directScripts, "direct-scripts", false, _directScriptsHelp)
+	flags.AddFlag(&pflag.Flag{
+		Name:  "args",
+		Usage: _argsHelp,
+		Value: &taskArgsValue{opts: opts},
+	})
+	flags.IntVar(&opts.failureRecapLines, "failure-recap-lines", 0, _failureRecapLinesHelp)
 	flags.BoolVar(&opts.noDaemon, "no-daemon", false, "Run without using turbo's daemon process")
 	flags.BoolVar(&opts.daemonOptIn, "experimental-use-daemon", false, "Use the experimental turbo daemon")
 	// Daemon-related flags hidden for now, we can unhide when daemon is ready.
@@ -511,6 +721,7 @@ func addRunOpts(opts *runOpts, flags *pflag.FlagSet, aliases map[string]string)
 		NoOptDefVal: _graphNoValue,
 		Value:       &graphValue{opts: opts},
 	})
+	flags.StringVar(&opts.graphType, "graph-type", "", _graphTypeHelp)
 }
 
 var _persistentFlags = []string{
@@ -541,6 +752,11 @@ func noopPersistentOptsDuringMigration(flags *pflag.FlagSet) {
 	}
 }
 
+const (
+	_taskGraphType    = "task"
+	_packageGraphType = "package"
+)
+
 const (
 	_graphText      = "graph"
 	_graphNoValue   = "<output filename>"
@@ -632,6 +848,165 @@ func (d *dryRunValue) Type() string {
 	return "/ dry "
 }
 
+const _continueDependenciesSuccessful = "dependencies-successful"
+
+// continueValue implements a flag that can be treated as a boolean (--continue)
+// or a string (--continue=dependencies-successful).
+type continueValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &continueValue{}
+
+func (c *continueValue) String() string {
+	if c.opts.skipFailedDependents {
+		return _continueDependenciesSuccessful
+	}
+	return strconv.FormatBool(c.opts.continueOnError)
+}
+
+func (c *continueValue) Set(value string) error {
+	if value == _continueDependenciesSuccessful {
+		c.opts.continueOnError = true
+		c.opts.skipFailedDependents = true
+		return nil
+	}
+	continueOnError, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid continue mode: %v", value)
+	}
+	c.opts.continueOnError = continueOnError
+	c.opts.skipFailedDependents = false
+	return nil
+}
+
+func (c *continueValue) Type() string {
+	return "mode"
+}
+
+// retriesValue is an integer flag which is unset by default, so that the
+// retries configured in turbo.json apply
+type retriesValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &retriesValue{}
+
+func (r *retriesValue) String() string {
+	if r.opts.retries == nil {
+		return ""
+	}
+	return strconv.Itoa(*r.opts.retries)
+}
+
+func (r *retriesValue) Set(value string) error {
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return fmt.Errorf("invalid number of retries: %v", value)
+	}
+	r.opts.retries = &retries
+	return nil
+}
+
+func (r *retriesValue) Type() string {
+	return "number"
+}
+
+// taskArgsValue is a repeatable flag of the form <task>=<args>, where the
+// arguments are split the way a shell would split them
+type taskArgsValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &taskArgsValue{}
+
+func (a *taskArgsValue) String() string {
+	tasks := make([]string, 0, len(a.opts.taskArgs))
+	for task := range a.opts.taskArgs {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+	values := make([]string, len(tasks))
+	for i, task := range tasks {
+		values[i] = task + "=" + shellquote.Join(a.opts.taskArgs[task]...)
+	}
+	return strings.Join(values, ",")
+}
+
+func (a *taskArgsValue) Set(value string) error {
+	task, rawArgs, ok := strings.Cut(value, "=")
+	if !ok || task == "" {
+		return fmt.Errorf("invalid value for --args: %q, expected <task>=<args>", value)
+	}
+	args, err := shellquote.Split(rawArgs)
+	if err != nil {
+		return fmt.Errorf("invalid arguments for %v: %w", task, err)
+	}
+	if a.opts.taskArgs == nil {
+		a.opts.taskArgs = make(map[string][]string)
+	}
+	a.opts.taskArgs[task] = append(a.opts.taskArgs[task], args...)
+	return nil
+}
+
+func (a *taskArgsValue) Type() string {
+	return "task=args"
+}
+
+// argsForTask returns the arguments given with --args for a task. The most specific
+// match wins: package#task@variant, package#task, task@variant, then task.
+func (o *runOpts) argsForTask(pt *nodes.PackageTask) ([]string, bool) {
+	candidates := []string{
+		pt.TaskID,
+		util.GetTaskId(pt.PackageName, pt.Script()),
+		pt.Task,
+		pt.Script(),
+	}
+	for _, candidate := range candidates {
+		if args, ok := o.taskArgs[candidate]; ok {
+			return args, true
+		}
+	}
+	return nil, false
+}
+
+// validate checks for flag values which are out of range
+func (o *runOpts) validate() error {
+	if o.memoryBudget < 0 {
+		return errors.New("--memory-budget cannot be negative")
+	}
+	if o.taskTimeout < 0 {
+		return errors.New("--task-timeout cannot be negative")
+	}
+	if o.failureRecapLines < 0 {
+		return errors.New("--failure-recap-lines cannot be negative")
+	}
+	if o.graphType != "" && o.graphType != _taskGraphType && o.graphType != _packageGraphType {
+		return fmt.Errorf("invalid value for --graph-type: %q, expected %q or %q", o.graphType, _taskGraphType, _packageGraphType)
+	}
+	return nil
+}
+
+// retriesForTask returns the number of times a failed task should be re-run
+func (o *runOpts) retriesForTask(pt *nodes.PackageTask) int {
+	if o.retries != nil {
+		return *o.retries
+	}
+	return pt.TaskDefinition.Retries
+}
+
+// timeoutForTask returns how long a task may run before it is stopped. 0 is unlimited.
+func (o *runOpts) timeoutForTask(pt *nodes.PackageTask) time.Duration {
+	if pt.TaskDefinition.Timeout > 0 {
+		return pt.TaskDefinition.Timeout
+	}
+	if pt.TaskDefinition.Persistent {
+		// Persistent tasks are expected to run until turbo exits
+		return 0
+	}
+	return o.taskTimeout
+}
+
 func getDefaultOptions(config *config.Config) *Opts {
 	return &Opts{
 		runOpts: runOpts{
@@ -667,19 +1042,11 @@ func (r *run) logWarning(prefix string, err error) {
 	r.ui.Error(fmt.Sprintf("%s%s%s", ui.WARNING_PREFIX, prefix, color.YellowString(" %v", err)))
 }
 
-func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec, engine *core.Scheduler, packageManager *packagemanager.PackageManager, hashes *taskhash.Tracker, startAt time.Time) error {
-	apiClient := r.config.NewClient()
-	var analyticsSink analytics.Sink
-	if r.config.IsLoggedIn() {
-		analyticsSink = apiClient
-	} else {
-		analyticsSink = analytics.NullSink
-	}
-	analyticsClient := analytics.NewClient(ctx, analyticsSink, r.config.Logger.Named("analytics"))
-	defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+// newCache sets up the caches that are enabled for this run
+func (r *run) newCache(rs *runSpec, apiClient *client.ApiClient, recorder analytics.Recorder) (cache.Cache, error) {
 	// Theoretically this is overkill, but bias towards not spamming the console
 	once := &sync.Once{}
-	turboCache, err := cache.New(rs.Opts.cacheOpts, r.config, apiClient, analyticsClient, func(_cache cache.Cache, err error) {
+	turboCache, err := cache.New(rs.Opts.cacheOpts, r.config, apiClient, recorder, func(_cache cache.Cache, err error) {
 		// Currently the HTTP Cache is the only one that can be disabled.
 		// With a cache system refactor, we might consider giving names to the caches so
 		// we can accurately report them here.
@@ -691,9 +1058,29 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		if errors.Is(err, cache.ErrNoCachesEnabled) {
 			r.logWarning("No caches are enabled. You can try \"turbo login\", \"turbo link\", or ensuring you are not passing --remote-only to enable caching", nil)
 		} else {
-			return errors.Wrap(err, "failed to set up caching")
+			return nil, errors.Wrap(err, "failed to set up caching")
 		}
 	}
+	return turboCache, nil
+}
+
+func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec, engine *core.Scheduler, packageManager *packagemanager.PackageManager, hashes *taskhash.Tracker, startAt time.Time) error {
+	apiClient := r.config.NewClient()
+	var analyticsSink analytics.Sink
+	if r.config.IsLoggedIn() {
+		analyticsSink = apiClient
+	} else {
+		analyticsSink = analytics.NullSink
+	}
+	analyticsClient := analytics.NewClient(ctx, analyticsSink, r.config.Logger.Named("analytics"))
+	defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+	if !rs.Opts.cacheOpts.SkipRemote {
+		r.ui.Output(ui.Dim("• Remote computation caching enabled"))
+	}
+	turboCache, err := r.newCache(rs, apiClient, analyticsClient)
+	if err != nil {
+		return err
+	}
 	defer turboCache.Shutdown()
 	colorCache := colorcache.New()
 	runState := NewRunState(startAt, rs.Opts.runOpts.profile, r.config)
@@ -723,22 +1110,54 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		processes:      r.processes,
 		taskHashes:     hashes,
 		argSeparator:   argSeparator,
+		taskDurations:  loadTaskDurations(rs.Opts.cacheOpts.Dir),
+		summary:        newRunSummary(startAt, r.config.TurboVersion, g, rs),
+		logsWritten:    make(util.Set),
+		previousRun:    &lastRun{},
+		repoRoot:       r.config.Cwd,
+		taskLocks:      newTaskLocks(r.config.Cwd, r.config.Logger.Named("locks")),
+	}
+	if rs.Opts.runOpts.resume {
+		ec.previousRun = loadLastRun(r.config.Cwd)
+	}
+	if rs.Opts.runOpts.directScripts {
+		directScripts, err := newDirectScripts(r.config.Cwd, packageManager, r.config.RootPackageJSON)
+		if err != nil {
+			r.logWarning("", err)
+		}
+		ec.directScripts = directScripts
 	}
 
 	// run the thing
+	execOpts := core.ExecOpts{
+		Parallel:      rs.Opts.runOpts.parallel,
+		Concurrency:   rs.Opts.runOpts.concurrency,
+		MemoryMB:      rs.Opts.runOpts.memoryBudget,
+		TaskDurations: ec.taskDurations.Estimates(),
+	}
+	if rs.Opts.runOpts.skipFailedDependents {
+		execOpts.Skipped = runState.Skip
+	}
 	errs := engine.Execute(g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
 		deps := engine.TaskGraph.DownEdges(pt.TaskID)
 		return ec.exec(ctx, pt, deps)
-	}), core.ExecOpts{
-		Parallel:    rs.Opts.runOpts.parallel,
-		Concurrency: rs.Opts.runOpts.concurrency,
-	})
+	}), execOpts)
+	if err := ec.taskDurations.Save(); err != nil {
+		r.logWarning("failed to save task durations", err)
+	}
+	if err := recordLastRun(r.config.Cwd, ec.taskLocks, ec.summary.updateLastRun); err != nil {
+		r.logWarning("failed to record the results of this run", err)
+	}
 
 	// Track if we saw any child with a non-zero exit code
 	exitCode := 0
 	exitCodeErr := &process.ChildExit{}
+	timeoutErr := &process.ChildTimeout{}
+	timedOut := false
 	for _, err := range errs {
-		if errors.As(err, &exitCodeErr) {
+		if errors.As(err, &timeoutErr) {
+			timedOut = true
+		} else if errors.As(err, &exitCodeErr) {
 			if exitCodeErr.ExitCode > exitCode {
 				exitCode = exitCodeErr.ExitCode
 			}
@@ -748,10 +1167,33 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		}
 		r.ui.Error(err.Error())
 	}
+	if rs.Opts.runOpts.failureRecapLines > 0 {
+		r.printFailureRecap(ctx, g, ec, colorCache, rs.Opts.runOpts.failureRecapLines)
+	}
+	if timedOut {
+		// Distinguish a hung task from one that failed
+		exitCode = process.ExitCodeTimeout
+	}
 
 	if err := runState.Close(r.ui, rs.Opts.runOpts.profile); err != nil {
 		return errors.Wrap(err, "error with profiler")
 	}
+	if rs.Opts.runOpts.reportJUnit != "" {
+		reportPath := fs.ResolveUnknownPath(r.config.Cwd, rs.Opts.runOpts.reportJUnit)
+		if err := r.writeJUnitReport(ctx, reportPath, g, ec, time.Since(startAt)); err != nil {
+			r.logWarning("", err)
+		}
+	}
+	if rs.Opts.runOpts.summarize {
+		ec.summary.complete(ctx, g, engine, runState.Skipped(), exitCode)
+		path, err := ec.summary.save(r.config.Cwd)
+		if err != nil {
+			r.logWarning("", err)
+		} else if relativePath, err := r.config.Cwd.RelativePathString(path.ToString()); err == nil {
+			r.ui.Output(fmt.Sprintf(ui.Dim("• Run summary: %v"), relativePath))
+			r.ui.Output("")
+		}
+	}
 	if exitCode != 0 {
 		return &process.ChildExit{
 			ExitCode: exitCode,
@@ -771,20 +1213,38 @@ type hashedTask struct {
 	Dir          string   `json:"directory"`
 	Dependencies []string `json:"dependencies"`
 	Dependents   []string `json:"dependents"`
+	Framework    string   `json:"framework"`
+	// Cache says which caches hold the task's outputs. It is null for tasks
+	// without a script in their package, which are never cached.
+	Cache *cache.ArtifactStatus `json:"cache"`
+	// restorable is whether the run would restore the task's outputs from the cache,
+	// which it doesn't for tasks with caching disabled, or with --force
+	restorable bool
+}
+
+// dryRunCacheSummary counts the tasks whose outputs are expected to be restored
+// from the cache, and the ones which are expected to run
+type dryRunCacheSummary struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
 }
 
 func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *completeGraph, taskHashes *taskhash.Tracker, rs *runSpec) ([]hashedTask, error) {
 	taskIDs := []hashedTask{}
 	errs := engine.Execute(g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
-		passThroughArgs := rs.ArgsForTask(pt.Task)
+		passThroughArgs := rs.ArgsForTask(pt)
 		deps := engine.TaskGraph.DownEdges(pt.TaskID)
 		hash, err := taskHashes.CalculateTaskHash(pt, deps, passThroughArgs)
 		if err != nil {
 			return err
 		}
 		command, ok := pt.Command()
+		var cacheStatus *cache.ArtifactStatus
 		if !ok {
 			command = "<NONEXISTENT>"
+		} else {
+			// Filled in by checkCache once every hash is known
+			cacheStatus = &cache.ArtifactStatus{}
 		}
 		isRootTask := pt.PackageName == util.RootPkgName
 		if isRootTask && commandLooksLikeTurbo(command) {
@@ -814,6 +1274,11 @@ func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *co
 		}
 		sort.Strings(stringDescendents)
 
+		framework := ""
+		if inferred := taskHashes.InferFramework(pt); inferred != nil {
+			framework = inferred.Slug
+		}
+
 		taskIDs = append(taskIDs, hashedTask{
 			TaskID:       pt.TaskID,
 			Task:         pt.Task,
@@ -825,6 +1290,9 @@ func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *co
 			LogFile:      pt.RepoRelativeLogFile(),
 			Dependencies: stringAncestors,
 			Dependents:   stringDescendents,
+			Framework:    framework,
+			Cache:        cacheStatus,
+			restorable:   pt.TaskDefinition.ShouldCache && !rs.Opts.runcacheOpts.SkipReads,
 		})
 		return nil
 	}), core.ExecOpts{
@@ -840,6 +1308,45 @@ func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *co
 	return taskIDs, nil
 }
 
+// checkCache fills in which caches hold the outputs of each task, without
+// retrieving them, and counts the expected cache hits and misses. Tasks whose
+// outputs wouldn't be restored aren't checked, and are misses. If a cache can't
+// be checked, a warning is printed and its artifacts are treated as missing.
+func (r *run) checkCache(turboCache cache.Cache, tasks []hashedTask) *dryRunCacheSummary {
+	var wg sync.WaitGroup
+	once := &sync.Once{}
+	for _, task := range tasks {
+		if task.Cache == nil || !task.restorable {
+			continue
+		}
+		wg.Add(1)
+		go func(task hashedTask) {
+			defer wg.Done()
+			status, err := cache.Exists(turboCache, task.Hash)
+			if err != nil {
+				once.Do(func() {
+					r.logWarning("Failed to check the cache", err)
+				})
+			}
+			*task.Cache = status
+		}(task)
+	}
+	wg.Wait()
+
+	summary := &dryRunCacheSummary{}
+	for _, task := range tasks {
+		if task.Cache == nil {
+			continue
+		}
+		if task.Cache.Local || task.Cache.Remote {
+			summary.Hits++
+		} else {
+			summary.Misses++
+		}
+	}
+	return summary
+}
+
 var _isTurbo = regexp.MustCompile(fmt.Sprintf("(?:^|%v|\\s)turbo(?:$|\\s)", regexp.QuoteMeta(string(filepath.Separator))))
 
 func commandLooksLikeTurbo(command string) bool {
@@ -855,6 +1362,38 @@ func validateTasks(pipeline fs.Pipeline, tasks []string) error {
 	return nil
 }
 
+// validateTaskArgs checks that each task given arguments with --args is in the
+// pipeline, since arguments for a misspelled task would never be used
+func validateTaskArgs(pipeline fs.Pipeline, taskArgs map[string][]string) error {
+	known := make(util.Set)
+	for key, definition := range pipeline {
+		task := key
+		if util.IsPackageTask(key) {
+			_, task = util.GetPackageTaskFromId(key)
+		}
+		known.Add(task)
+		// Arguments for a task's script also apply to each of its variants
+		if definition.IsVariant() {
+			known.Add(definition.Variant.Task)
+		}
+	}
+	keys := make([]string, 0, len(taskArgs))
+	for key := range taskArgs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		task := key
+		if util.IsPackageTask(key) {
+			_, task = util.GetPackageTaskFromId(key)
+		}
+		if !known.Includes(task) {
+			return fmt.Errorf("invalid value for --args: task `%v` not found in turbo `pipeline` in \"turbo.json\", expected <task>, <task>@<variant> or <package>#<task>", key)
+		}
+	}
+	return nil
+}
+
 type execContext struct {
 	colorCache     *colorcache.ColorCache
 	runState       *RunState
@@ -867,6 +1406,41 @@ type execContext struct {
 	processes      *process.Manager
 	taskHashes     *taskhash.Tracker
 	argSeparator   []string
+	taskDurations  *taskDurations
+	summary        *runSummary
+	// previousRun is only populated with --resume
+	previousRun *lastRun
+	repoRoot    fs.AbsolutePath
+	// taskLocks keeps other turbo processes from executing the same tasks at the same time
+	taskLocks *taskLocks
+	// directScripts is only set with --direct-scripts
+	directScripts *directScripts
+
+	// packageLocks runs the tasks in each package one at a time with --check-outputs
+	packageLocks packageLocks
+
+	logsMu sync.Mutex
+	// logsWritten is the tasks whose log file was written by this run
+	logsWritten util.Set
+}
+
+// logWritten records that this run has written the log file of a task
+func (e *execContext) logWritten(taskID string) {
+	e.logsMu.Lock()
+	defer e.logsMu.Unlock()
+	e.logsWritten.Add(taskID)
+}
+
+// hasCurrentLog returns whether the log file of a task was written by this run, or
+// restored from the cache. Tasks whose outputs aren't cached don't write a log, so
+// any other log file was left over from an earlier run.
+func (e *execContext) hasCurrentLog(state BuildTargetState) bool {
+	if state.Status == TargetCached {
+		return true
+	}
+	e.logsMu.Lock()
+	defer e.logsMu.Unlock()
+	return e.logsWritten.Includes(state.Label)
 }
 
 func (e *execContext) logError(log hclog.Logger, prefix string, err error) {
@@ -899,7 +1473,7 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 		WarnPrefix:   prettyTaskPrefix,
 	}
 
-	passThroughArgs := e.rs.ArgsForTask(pt.Task)
+	passThroughArgs := e.rs.ArgsForTask(pt)
 	hash, err := e.taskHashes.CalculateTaskHash(pt, deps, passThroughArgs)
 	e.logger.Debug("task hash", "value", hash)
 	if err != nil {
@@ -916,46 +1490,213 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 		targetLogger.Debug("done", "status", "skipped", "duration", time.Since(cmdTime))
 		return nil
 	}
-	// Cache ---------------------------------------------
+	summary := e.summary.startTask(pt, hash, e.taskHashes.EnvVarNames(pt.TaskID), cmdTime)
 	taskCache := e.runCache.TaskCache(pt, hash)
-	hit, err := taskCache.RestoreOutputs(ctx, targetUi, targetLogger)
+	// Resume --------------------------------------------
+	// The task's outputs are still on disk from the previous run, whether or not they were cached
+	if e.previousRun.succeeded(pt.TaskID, hash) {
+		if taskCache.OutputMode() != util.NoTaskOutput {
+			targetUi.Output(fmt.Sprintf("succeeded in previous run, skipping %s", ui.Dim(hash)))
+		}
+		e.recordOutputsHash(pt, taskCache, targetLogger)
+		tracer(TargetResumed, nil)
+		summary.finish(_taskStatusResumed, nil)
+		return nil
+	}
+	// Lock ----------------------------------------------
+	// If another turbo process is executing this task, wait for it to finish, so
+	// that the task's outputs can be restored from the cache instead. The lock is
+	// held until the outputs have been cached. Without cache reads or writes, the
+	// outputs couldn't be shared, so there is nothing to wait for.
+	release := func() {}
+	defer func() { release() }()
+	runcacheOpts := e.rs.Opts.runcacheOpts
+	if pt.TaskDefinition.ShouldCache && !runcacheOpts.SkipReads && !runcacheOpts.SkipWrites {
+		// The other process's task can't take longer than its timeout
+		maxWait := _taskLockMaxWait
+		if pt.TaskDefinition.Timeout > 0 {
+			maxWait = pt.TaskDefinition.Timeout
+		}
+		unlock, err := e.taskLocks.acquire(ctx, hash, maxWait, func(pid int) {
+			targetUi.Output(fmt.Sprintf("waiting for another turbo process (pid %v) which is executing this task", pid))
+		})
+		if errors.Is(err, errTaskLockWaitExceeded) {
+			targetUi.Warn(ui.Dim(fmt.Sprintf("%v after %v, executing it anyway", err, maxWait)))
+		} else if err != nil {
+			if ctx.Err() != nil {
+				summary.finish(_taskStatusStopped, nil)
+				return nil
+			}
+			targetUi.Warn(ui.Dim(fmt.Sprintf("could not lock task, it may also be executed by another turbo process: %v", err)))
+		} else {
+			release = unlock
+		}
+	}
+	// With --check-outputs, the tasks in a package take turns, so that the files
+	// that a task writes, or restores from the cache, aren't blamed on another task.
+	// Persistent tasks never finish, so they can't wait their turn.
+	if e.rs.Opts.runOpts.checkOutputs && !pt.TaskDefinition.Persistent {
+		unlockPackage := e.packageLocks.lock(pt.Pkg.Dir)
+		defer unlockPackage()
+	}
+	// Cache ---------------------------------------------
+	cacheStatus, err := taskCache.RestoreOutputs(ctx, targetUi, targetLogger)
 	if err != nil {
 		targetUi.Error(fmt.Sprintf("error fetching from cache: %s", err))
-	} else if hit {
+	} else if cacheStatus.Hit {
+		e.recordOutputsHash(pt, taskCache, targetLogger)
+		// The cache records how long the task took to execute, so a run which is
+		// mostly cache hits, such as on a new CI machine, still learns durations
+		if cacheStatus.Duration > 0 {
+			e.taskDurations.Record(pt.TaskID, time.Duration(cacheStatus.Duration)*time.Millisecond)
+		}
 		tracer(TargetCached, nil)
+		summary.cacheHit(cacheStatus)
+		summary.finish(_taskStatusCached, nil)
 		return nil
 	}
 	// Setup command execution
-	argsactual := append([]string{"run"}, pt.Task)
+	argsactual := append([]string{"run"}, pt.Script())
 	if len(passThroughArgs) > 0 {
 		// This will be either '--' or a typed nil
 		argsactual = append(argsactual, e.argSeparator...)
 		argsactual = append(argsactual, passThroughArgs...)
 	}
 
-	cmd := exec.Command(e.packageManager.Command, argsactual...)
-	cmd.Dir = pt.Pkg.Dir
-	envs := fmt.Sprintf("TURBO_HASH=%v", hash)
-	cmd.Env = append(os.Environ(), envs)
-
-	// Setup stdout/stderr
-	// If we are not caching anything, then we don't need to write logs to disk
-	// be careful about this conditional given the default of cache = true
-	writer, err := taskCache.OutputWriter()
+	dotEnvPairs, err := env.GetDotEnvPairs(pt.Pkg.Dir, pt.TaskDefinition.DotEnv)
 	if err != nil {
 		tracer(TargetBuildFailed, err)
+		summary.finish(_taskStatusFailed, err)
 		e.logError(targetLogger, prettyTaskPrefix, err)
 		if !e.rs.Opts.runOpts.continueOnError {
-			os.Exit(1)
+			e.processes.Close()
+		}
+		return err
+	}
+	envs := fmt.Sprintf("TURBO_HASH=%v", hash)
+	// When a variable is set more than once, the last value wins. Variables
+	// already set in turbo's environment take precedence over .env files, and
+	// the environment of a matrix variant takes precedence over both.
+	cmdEnv := append(dotEnvPairs, os.Environ()...)
+	if pt.TaskDefinition.IsVariant() {
+		cmdEnv = append(cmdEnv, pt.TaskDefinition.Variant.EnvPairs()...)
+	}
+	cmdEnv = append(cmdEnv, envs)
+
+	// Files that the task writes are compared against what was there beforehand.
+	// Tasks which aren't cached don't have to declare their outputs.
+	var outputsSnapshot packageSnapshot
+	if e.rs.Opts.runOpts.checkOutputs && pt.TaskDefinition.ShouldCache {
+		outputsSnapshot, err = snapshotPackage(e.repoRoot, pt.Pkg.Dir)
+		if err != nil {
+			targetUi.Warn(ui.Dim(fmt.Sprintf("could not check outputs: %v", err)))
 		}
 	}
+
+	retries := e.rs.Opts.runOpts.retriesForTask(pt)
+	timeout := e.rs.Opts.runOpts.timeoutForTask(pt)
+	var closeOutputs func() error
+	// attemptTime is when the latest attempt started. Failed attempts and the delays
+	// between them aren't part of how long the task takes to execute.
+	var attemptTime time.Time
+	for attempt := 1; ; attempt++ {
+		attemptTime = time.Now()
+		var cmds []*exec.Cmd
+		if e.directScripts != nil {
+			cmds = e.directScripts.commands(pt, passThroughArgs, cmdEnv)
+		} else {
+			cmd := exec.Command(e.packageManager.Command, argsactual...)
+			cmd.Dir = pt.Pkg.Dir
+			cmd.Env = cmdEnv
+			cmds = []*exec.Cmd{cmd}
+		}
+		// Setup stdout/stderr
+		// If we are not caching anything, then we don't need to write logs to disk
+		// be careful about this conditional given the default of cache = true
+		writer, err := taskCache.OutputWriter()
+		if err != nil {
+			tracer(TargetBuildFailed, err)
+			e.logError(targetLogger, prettyTaskPrefix, err)
+			if !e.rs.Opts.runOpts.continueOnError {
+				os.Exit(1)
+			}
+		} else if taskCache.WritesLog() {
+			e.logWritten(pt.TaskID)
+		}
+		closeOutputs, err = e.runCommands(cmds, writer, prettyTaskPrefix, timeout)
+		if err == nil {
+			break
+		}
+		// if we already know we're in the process of exiting,
+		// we don't need to record an error to that effect.
+		if errors.Is(err, process.ErrClosing) {
+			summary.finish(_taskStatusStopped, nil)
+			return nil
+		}
+		if attempt > retries {
+			var timeoutErr *process.ChildTimeout
+			if errors.As(err, &timeoutErr) {
+				tracer(TargetBuildTimedOut, err)
+				summary.finish(_taskStatusTimedOut, err)
+			} else {
+				tracer(TargetBuildFailed, err)
+				summary.finish(_taskStatusFailed, err)
+			}
+			targetLogger.Error("Error: command finished with error: %w", err)
+			if !e.rs.Opts.runOpts.continueOnError {
+				targetUi.Error(fmt.Sprintf("ERROR: command finished with error: %s", err))
+				e.processes.Close()
+			} else {
+				targetUi.Warn("command finished with error, but continuing...")
+			}
+			return err
+		}
+		e.keepAttemptLog(taskCache, attempt, targetLogger)
+		e.runState.Retry(pt.TaskID)
+		targetUi.Warn(fmt.Sprintf("command finished with error: %s, retrying (attempt %v of %v)...", err, attempt+1, retries+1))
+		select {
+		case <-time.After(pt.TaskDefinition.RetryDelay):
+		case <-ctx.Done():
+			summary.finish(_taskStatusStopped, nil)
+			return nil
+		}
+	}
+
+	duration := time.Since(attemptTime)
+	// Close off our outputs and cache them
+	if err := closeOutputs(); err != nil {
+		e.logError(targetLogger, "", err)
+	} else {
+		// The lock is released by SaveOutputs once the outputs are in the cache
+		saved := release
+		release = func() {}
+		if err = taskCache.SaveOutputs(ctx, targetLogger, targetUi, int(duration.Milliseconds()), saved); err != nil {
+			e.logError(targetLogger, "", fmt.Errorf("error caching output: %w", err))
+		}
+	}
+
+	if outputsSnapshot != nil {
+		e.reportOutputs(pt, outputsSnapshot, targetUi)
+	}
+	e.recordOutputsHash(pt, taskCache, targetLogger)
+	e.taskDurations.Record(pt.TaskID, duration)
+
+	// Clean up tracing
+	tracer(TargetBuilt, nil)
+	summary.finish(_taskStatusBuilt, nil)
+	targetLogger.Debug("done", "status", "complete", "duration", time.Since(cmdTime))
+	return nil
+}
+
+// runCommands runs a single attempt of a task's commands, one after another, streaming their output to the
+// terminal and to writer. A timeout of 0 lets the commands run forever, otherwise it applies to all of them
+// together. If every command succeeds, the returned function must be called to flush and close the log file.
+func (e *execContext) runCommands(cmds []*exec.Cmd, writer io.WriteCloser, prettyTaskPrefix string, timeout time.Duration) (func() error, error) {
 	logger := log.New(writer, "", 0)
 	// Setup a streamer that we'll pipe cmd.Stdout to
 	logStreamerOut := logstreamer.NewLogstreamer(logger, prettyTaskPrefix, false)
 	// Setup a streamer that we'll pipe cmd.Stderr to.
 	logStreamerErr := logstreamer.NewLogstreamer(logger, prettyTaskPrefix, false)
-	cmd.Stderr = logStreamerErr
-	cmd.Stdout = logStreamerOut
 	// Flush/Reset any error we recorded
 	logStreamerErr.FlushRecord()
 	logStreamerOut.FlushRecord()
@@ -980,40 +1721,64 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 		return nil
 	}
 
-	// Run the command
-	if err := e.processes.Exec(cmd); err != nil {
-		// close off our outputs. We errored, so we mostly don't care if we fail to close
-		_ = closeOutputs()
-		// if we already know we're in the process of exiting,
-		// we don't need to record an error to that effect.
-		if errors.Is(err, process.ErrClosing) {
-			return nil
+	// Run the commands
+	deadline := time.Now().Add(timeout)
+	for _, cmd := range cmds {
+		cmd.Stderr = logStreamerErr
+		cmd.Stdout = logStreamerOut
+		remaining := time.Duration(0)
+		if timeout > 0 {
+			remaining = time.Until(deadline)
+			if remaining <= 0 {
+				_ = closeOutputs()
+				return nil, &process.ChildTimeout{Timeout: timeout, Command: cmd.String()}
+			}
 		}
-		tracer(TargetBuildFailed, err)
-		targetLogger.Error("Error: command finished with error: %w", err)
-		if !e.rs.Opts.runOpts.continueOnError {
-			targetUi.Error(fmt.Sprintf("ERROR: command finished with error: %s", err))
-			e.processes.Close()
-		} else {
-			targetUi.Warn("command finished with error, but continuing...")
+		if err := e.processes.ExecWithTimeout(cmd, remaining); err != nil {
+			// close off our outputs. We errored, so we mostly don't care if we fail to close
+			_ = closeOutputs()
+			var timeoutErr *process.ChildTimeout
+			if errors.As(err, &timeoutErr) {
+				// Report the timeout of the whole task, rather than what was left of it
+				timeoutErr.Timeout = timeout
+			}
+			return nil, err
 		}
-		return err
 	}
+	return closeOutputs, nil
+}
 
-	duration := time.Since(cmdTime)
-	// Close off our outputs and cache them
-	if err := closeOutputs(); err != nil {
-		e.logError(targetLogger, "", err)
-	} else {
-		if err = taskCache.SaveOutputs(ctx, targetLogger, targetUi, int(duration.Milliseconds())); err != nil {
-			e.logError(targetLogger, "", fmt.Errorf("error caching output: %w", err))
-		}
+// keepAttemptLog moves the log file of a failed attempt aside, so that it isn't
+// overwritten by the next attempt. The final attempt's log is the one that is cached.
+func (e *execContext) keepAttemptLog(taskCache runcache.TaskCache, attempt int, logger hclog.Logger) {
+	logFile := taskCache.LogFileName
+	if !logFile.FileExists() {
+		return
+	}
+	attemptLogFile := fs.AbsolutePath(strings.TrimSuffix(logFile.ToString(), ".log") + fmt.Sprintf("-attempt-%v.log", attempt))
+	if err := os.Rename(logFile.ToString(), attemptLogFile.ToString()); err != nil {
+		logger.Warn(fmt.Sprintf("failed to keep log of attempt %v: %v", attempt, err))
 	}
+}
 
-	// Clean up tracing
-	tracer(TargetBuilt, nil)
-	targetLogger.Debug("done", "status", "complete", "duration", duration)
-	return nil
+// recordOutputsHash makes the contents of a completed task's outputs available for hashing its
+// dependents, if we are hashing dependencies by their outputs.
+func (e *execContext) recordOutputsHash(pt *nodes.PackageTask, taskCache runcache.TaskCache, logger hclog.Logger) {
+	if !e.rs.Opts.runOpts.earlyCutoff {
+		return
+	}
+	outputsHash, err := taskCache.OutputsHash()
+	if err != nil {
+		// Dependents will fall back to using the task hash, which is always safe
+		logger.Warn(fmt.Sprintf("failed to hash outputs of %v: %v", pt.TaskID, err))
+		return
+	}
+	if outputsHash == "" {
+		// Without outputs, dependents use the task hash
+		return
+	}
+	logger.Debug("outputs hash", "value", outputsHash)
+	e.taskHashes.SetTaskOutputsHash(pt.TaskID, outputsHash)
 }
 
 func (g *completeGraph) getPackageTaskVisitor(ctx gocontext.Context, visitor func(ctx gocontext.Context, pt *nodes.PackageTask) error) func(taskID string) error {
@@ -1045,3 +1810,63 @@ func (g *completeGraph) getPackageTaskVisitor(ctx gocontext.Context, visitor fun
 		})
 	}
 }
+
+// describeTask returns the metadata shown for a task when rendering the task graph
+func (g *completeGraph) describeTask(taskID string) graphvisualizer.Node {
+	pkgName, task := util.GetPackageTaskFromId(taskID)
+	node := graphvisualizer.Node{
+		ID:      taskID,
+		Package: pkgName,
+		Task:    task,
+	}
+	// Errors only mean there's nothing more to say about the task
+	_ = g.getPackageTaskVisitor(gocontext.Background(), func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+		node.Directory = pt.Pkg.Dir
+		if command, ok := pt.Command(); ok {
+			node.Command = command
+		}
+		node.Outputs = pt.TaskDefinition.Outputs
+		shouldCache := pt.TaskDefinition.ShouldCache
+		node.Cache = &shouldCache
+		node.Persistent = pt.TaskDefinition.Persistent
+		return nil
+	})(taskID)
+	return node
+}
+
+// describePackage returns the metadata shown for a package when rendering the package graph
+func (g *completeGraph) describePackage(pkgName string) graphvisualizer.Node {
+	node := graphvisualizer.Node{
+		ID:      pkgName,
+		Package: pkgName,
+	}
+	if pkg, ok := g.PackageInfos[pkgName]; ok {
+		node.Directory = pkg.Dir
+	}
+	return node
+}
+
+// packageGraph returns the subset of the package graph made up of the given
+// packages and everything they depend on
+func (g *completeGraph) packageGraph(packages util.Set) *dag.AcyclicGraph {
+	graph := &dag.AcyclicGraph{}
+	for _, pkg := range packages {
+		if !g.TopologicalGraph.HasVertex(pkg) {
+			continue
+		}
+		graph.Add(pkg)
+		deps, err := g.TopologicalGraph.Ancestors(pkg)
+		if err != nil {
+			continue
+		}
+		for _, dep := range deps {
+			graph.Add(dep)
+		}
+	}
+	for _, edge := range g.TopologicalGraph.Edges() {
+		if graph.HasVertex(edge.Source()) && graph.HasVertex(edge.Target()) {
+			graph.Connect(edge)
+		}
+	}
+	return graph
+}
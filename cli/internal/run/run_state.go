@@ -46,10 +46,13 @@ const (
 	TargetBuilt
 	TargetCached
 	TargetBuildFailed
+	TargetBuildTimedOut
 	TargetTesting
 	TargetTestStopped
 	TargetTested
 	TargetTestFailed
+	// TargetResumed is a task which wasn't run because it succeeded in the previous run
+	TargetResumed
 )
 
 type BuildTargetState struct {
@@ -67,14 +70,20 @@ type BuildTargetState struct {
 }
 
 type RunState struct {
-	mu      sync.Mutex
-	Ordered []string
-	state   map[string]*BuildTargetState
-	Success int
-	Failure int
+	mu       sync.Mutex
+	Ordered  []string
+	state    map[string]*BuildTargetState
+	Success  int
+	Failure  int
+	TimedOut int
 	// Is the output streaming?
 	Cached    int
+	Resumed   int
 	Attempted int
+	// retries counts how many times each task has been re-run after failing
+	retries map[string]int
+	// skipped are the tasks which didn't run because a dependency failed
+	skipped []string
 
 	startedAt time.Time
 	config    *config.Config
@@ -92,6 +101,7 @@ func NewRunState(startedAt time.Time, tracingProfile string, config *config.Conf
 		Cached:    0,
 		Attempted: 0,
 		state:     make(map[string]*BuildTargetState),
+		retries:   make(map[string]int),
 
 		startedAt: startedAt,
 		config:    config,
@@ -118,6 +128,15 @@ func (r *RunState) Run(label string) func(outcome RunResultStatus, err error) {
 				Err:         fmt.Errorf("running %v failed: %w", label, err),
 				Description: fmt.Sprintf("running %v failed", label),
 			}, label, false)
+		case outcome == TargetBuildTimedOut:
+			r.add(&RunResult{
+				Time:        time.Now(),
+				Duration:    time.Since(start),
+				Label:       label,
+				Status:      TargetBuildTimedOut,
+				Err:         fmt.Errorf("running %v timed out: %w", label, err),
+				Description: fmt.Sprintf("running %v timed out", label),
+			}, label, false)
 		case outcome == TargetCached:
 			r.add(&RunResult{
 				Time:        time.Now(),
@@ -126,6 +145,14 @@ func (r *RunState) Run(label string) func(outcome RunResultStatus, err error) {
 				Description: label + " cached",
 				Status:      TargetCached,
 			}, label, false)
+		case outcome == TargetResumed:
+			r.add(&RunResult{
+				Time:        time.Now(),
+				Duration:    time.Since(start),
+				Label:       label,
+				Description: label + " resumed",
+				Status:      TargetResumed,
+			}, label, false)
 		case outcome == TargetBuildStopped:
 			r.add(&RunResult{
 				Time:        time.Now(),
@@ -149,6 +176,52 @@ func (r *RunState) Run(label string) func(outcome RunResultStatus, err error) {
 	}
 }
 
+// Retry records that a failed task is being run again
+func (r *RunState) Retry(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries[label]++
+}
+
+// Skip records that a task didn't run because one of its dependencies failed
+func (r *RunState) Skip(label string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped = append(r.skipped, label)
+}
+
+// Skipped returns the tasks which didn't run because one of their dependencies failed
+func (r *RunState) Skipped() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.skipped...)
+}
+
+// TargetStates returns a copy of the state of each task that has started, in the
+// order they started
+func (r *RunState) TargetStates() []BuildTargetState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]BuildTargetState, len(r.Ordered))
+	for i, label := range r.Ordered {
+		states[i] = *r.state[label]
+	}
+	return states
+}
+
+// FlakyTasks returns the tasks which succeeded only after being retried
+func (r *RunState) FlakyTasks() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var flaky []string
+	for _, label := range r.Ordered {
+		if r.retries[label] > 0 && r.state[label].Status == TargetBuilt {
+			flaky = append(flaky, label)
+		}
+	}
+	return flaky
+}
+
 func (r *RunState) add(result *RunResult, previous string, active bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -172,9 +245,15 @@ func (r *RunState) add(result *RunResult, previous string, active bool) {
 	case result.Status == TargetBuildFailed:
 		r.Failure++
 		r.Attempted++
+	case result.Status == TargetBuildTimedOut:
+		r.TimedOut++
+		r.Attempted++
 	case result.Status == TargetCached:
 		r.Cached++
 		r.Attempted++
+	case result.Status == TargetResumed:
+		r.Resumed++
+		r.Attempted++
 	case result.Status == TargetBuilt:
 		r.Success++
 		r.Attempted++
@@ -216,6 +295,8 @@ func (r *RunState) Render(ui cli.Ui, startAt time.Time, renderCount int, lineBuf
 				ui.Output(util.Sprintf("${GREEN}%s %s%s(%s)${RESET}", " ✓ ", k, strings.Repeat(".", fill-len(d)), d))
 			case TargetBuildFailed:
 				ui.Output(util.Sprintf("${RED}%s %s%s(%s)${RESET}", " ˣ ", k, strings.Repeat(".", fill-len(d)), d))
+			case TargetBuildTimedOut:
+				ui.Output(util.Sprintf("${RED}%s %s%s(%s)${RESET}", " ⧗ ", k, strings.Repeat(".", fill-len(d)), d))
 			default:
 				ui.Output(util.Sprintf("${GREY}%s %s%s(%s)${RESET}", " ✓ ", k, strings.Repeat(".", fill-len(d)), d))
 			}
@@ -252,9 +333,22 @@ func (r *RunState) Close(Ui cli.Ui, filename string) error {
 		maybeFullTurbo = ui.Rainbow(">>> FULL TURBO")
 	}
 	Ui.Output("") // Clear the line
-	Ui.Output(util.Sprintf("${BOLD} Tasks:${BOLD_GREEN}    %v successful${RESET}${GRAY}, %v total${RESET}", r.Cached+r.Success, r.Attempted))
+	maybeTimedOut := ""
+	if r.TimedOut > 0 {
+		maybeTimedOut = util.Sprintf("${GRAY}, ${RED}%v timed out${RESET}", r.TimedOut)
+	}
+	Ui.Output(util.Sprintf("${BOLD} Tasks:${BOLD_GREEN}    %v successful${RESET}%v${GRAY}, %v total${RESET}", r.Cached+r.Success+r.Resumed, maybeTimedOut, r.Attempted))
 	Ui.Output(util.Sprintf("${BOLD}Cached:    %v cached${RESET}${GRAY}, %v total${RESET}", r.Cached, r.Attempted))
+	if r.Resumed > 0 {
+		Ui.Output(util.Sprintf("${BOLD}Resumed:   %v succeeded in the previous run${RESET}", r.Resumed))
+	}
 	Ui.Output(util.Sprintf("${BOLD}  Time:    %v${RESET} %v${RESET}", time.Since(r.startedAt).Truncate(time.Millisecond), maybeFullTurbo))
+	if len(r.skipped) > 0 {
+		Ui.Output(util.Sprintf("${BOLD}Skipped:${RESET}${GRAY}   %v${RESET}", strings.Join(r.skipped, ", ")))
+	}
+	if flaky := r.FlakyTasks(); len(flaky) > 0 {
+		Ui.Output(util.Sprintf("${BOLD} Flaky:${RESET}${YELLOW}    %v${RESET}", strings.Join(flaky, ", ")))
+	}
 	Ui.Output("")
 	return nil
 }
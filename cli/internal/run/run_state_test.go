@@ -0,0 +1,75 @@
+package run
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/config"
+)
+
+func TestFlakyTasks(t *testing.T) {
+	rs := NewRunState(time.Now(), "", &config.Config{})
+
+	flaky := rs.Run("a#build")
+	rs.Retry("a#build")
+	flaky(TargetBuilt, nil)
+
+	failed := rs.Run("b#build")
+	rs.Retry("b#build")
+	failed(TargetBuildFailed, errors.New("exit status 1"))
+
+	rs.Run("c#build")(TargetBuilt, nil)
+
+	got := rs.FlakyTasks()
+	expected := []string{"a#build"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FlakyTasks() got %v, want %v", got, expected)
+	}
+}
+
+func TestTimedOutTasks(t *testing.T) {
+	rs := NewRunState(time.Now(), "", &config.Config{})
+
+	rs.Run("a#test")(TargetBuildTimedOut, errors.New("command timed out after 1m0s"))
+	rs.Run("b#test")(TargetBuildFailed, errors.New("exit status 1"))
+
+	if rs.TimedOut != 1 {
+		t.Errorf("TimedOut got %v, want 1", rs.TimedOut)
+	}
+	if rs.Failure != 1 {
+		t.Errorf("Failure got %v, want 1", rs.Failure)
+	}
+	if rs.Attempted != 2 {
+		t.Errorf("Attempted got %v, want 2", rs.Attempted)
+	}
+}
+
+func TestSkippedTasks(t *testing.T) {
+	rs := NewRunState(time.Now(), "", &config.Config{})
+	rs.Run("a#build")(TargetBuildFailed, errors.New("exit status 1"))
+	rs.Skip("b#build")
+	rs.Skip("c#build")
+
+	expected := []string{"b#build", "c#build"}
+	if !reflect.DeepEqual(rs.skipped, expected) {
+		t.Errorf("skipped got %v, want %v", rs.skipped, expected)
+	}
+}
+
+func TestResumedTasks(t *testing.T) {
+	rs := NewRunState(time.Now(), "", &config.Config{})
+	rs.Run("a#build")(TargetResumed, nil)
+	rs.Run("b#build")(TargetCached, nil)
+
+	if rs.Resumed != 1 {
+		t.Errorf("Resumed got %v, want 1", rs.Resumed)
+	}
+	if rs.Cached != 1 {
+		t.Errorf("Cached got %v, want 1", rs.Cached)
+	}
+	if rs.Attempted != 2 {
+		t.Errorf("Attempted got %v, want 2", rs.Attempted)
+	}
+}
@@ -3,14 +3,20 @@ package run
 import (
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/mitchellh/cli"
 	"github.com/pyr-sh/dag"
 	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/cache"
 	"github.com/vercel/turborepo/cli/internal/config"
 	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
 	"github.com/vercel/turborepo/cli/internal/runcache"
 	"github.com/vercel/turborepo/cli/internal/scope"
 	"github.com/vercel/turborepo/cli/internal/ui"
@@ -296,6 +302,201 @@ func TestParseConfig(t *testing.T) {
 			},
 			[]string{"foo"},
 		},
+		{
+			"retries",
+			[]string{"foo", "--retries=3"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					retries:     intPtr(3),
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"continue with successful dependencies",
+			[]string{"foo", "--continue=dependencies-successful"},
+			&Opts{
+				runOpts: runOpts{
+					continueOnError:      true,
+					skipFailedDependents: true,
+					concurrency:          10,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"task timeout",
+			[]string{"foo", "--task-timeout=15m"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					taskTimeout: 15 * time.Minute,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"summarize",
+			[]string{"foo", "--summarize"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					summarize:   true,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"junit report",
+			[]string{"foo", "--report-junit=reports/turbo.xml"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					reportJUnit: "reports/turbo.xml",
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"failure recap lines",
+			[]string{"foo", "--failure-recap-lines=50"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency:       10,
+					failureRecapLines: 50,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"resume",
+			[]string{"foo", "--resume", "--no-cache"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					resume:      true,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{
+					SkipWrites: true,
+				},
+				scopeOpts: scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"check outputs",
+			[]string{"foo", "--check-outputs"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency:  10,
+					checkOutputs: true,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"task args",
+			[]string{"build", "test", "--args", "test=--coverage --reporter='github actions'", "--args=web#build=--prod", "--", "--verbose"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					taskArgs: map[string][]string{
+						"test":      {"--coverage", "--reporter=github actions"},
+						"web#build": {"--prod"},
+					},
+					passThroughArgs: []string{"--verbose"},
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"build", "test"},
+		},
+		{
+			"direct scripts",
+			[]string{"foo", "--direct-scripts"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency:   10,
+					directScripts: true,
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
+		{
+			"package graph",
+			[]string{"foo", "--graph=graph.json", "--graph-type=package"},
+			&Opts{
+				runOpts: runOpts{
+					concurrency: 10,
+					graphFile:   "graph.json",
+					graphType:   "package",
+				},
+				cacheOpts: cache.Opts{
+					Dir:     defaultCacheFolder,
+					Workers: 10,
+				},
+				runcacheOpts: runcache.Opts{},
+				scopeOpts:    scope.Opts{},
+			},
+			[]string{"foo"},
+		},
 	}
 
 	cf := &config.Config{
@@ -323,6 +524,10 @@ func TestParseConfig(t *testing.T) {
 	}
 }
 
+func intPtr(i int) *int {
+	return &i
+}
+
 func TestParseRunOptionsUsesCWDFlag(t *testing.T) {
 	defaultCwd, err := fs.GetCwd()
 	if err != nil {
@@ -430,6 +635,118 @@ func Test_taskSelfRef(t *testing.T) {
 	}
 }
 
+func Test_validatePersistentConcurrency(t *testing.T) {
+	topoGraph := &dag.AcyclicGraph{}
+	topoGraph.Add("a")
+	topoGraph.Add("b")
+
+	pipeline := map[string]fs.TaskDefinition{
+		"dev": {
+			Persistent: true,
+			Resources:  fs.TaskResources{MemoryMB: 1000},
+		},
+	}
+	filteredPkgs := make(util.Set)
+	filteredPkgs.Add("a")
+	filteredPkgs.Add("b")
+	rs := &runSpec{
+		FilteredPkgs: filteredPkgs,
+		Targets:      []string{"dev"},
+		Opts:         &Opts{},
+	}
+	engine, err := buildTaskGraph(topoGraph, pipeline, rs)
+	if err != nil {
+		t.Fatalf("failed to build task graph: %v", err)
+	}
+	if err := validatePersistentConcurrency(engine, runOpts{concurrency: 2}); err == nil {
+		t.Error("expected an error running 2 persistent tasks with a concurrency of 2")
+	}
+	if err := validatePersistentConcurrency(engine, runOpts{concurrency: 3}); err != nil {
+		t.Errorf("expected no error running 2 persistent tasks with a concurrency of 3, got %v", err)
+	}
+	if err := validatePersistentConcurrency(engine, runOpts{concurrency: 1, parallel: true}); err != nil {
+		t.Errorf("expected no error running persistent tasks in parallel, got %v", err)
+	}
+	if err := validatePersistentConcurrency(engine, runOpts{concurrency: 3, memoryBudget: 2000}); err == nil {
+		t.Error("expected an error running 2 persistent tasks using 2000MB with a memory budget of 2000MB")
+	}
+	if err := validatePersistentConcurrency(engine, runOpts{concurrency: 3, memoryBudget: 4000}); err != nil {
+		t.Errorf("expected no error running 2 persistent tasks using 2000MB with a memory budget of 4000MB, got %v", err)
+	}
+}
+
+func Test_timeoutForTask(t *testing.T) {
+	opts := runOpts{taskTimeout: 10 * time.Minute}
+	cases := []struct {
+		Name     string
+		Task     fs.TaskDefinition
+		Expected time.Duration
+	}{
+		{"flag", fs.TaskDefinition{}, 10 * time.Minute},
+		{"configured", fs.TaskDefinition{Timeout: time.Minute}, time.Minute},
+		{"persistent", fs.TaskDefinition{Persistent: true}, 0},
+		{"configured persistent", fs.TaskDefinition{Persistent: true, Timeout: time.Hour}, time.Hour},
+	}
+	for _, tc := range cases {
+		pt := &nodes.PackageTask{TaskDefinition: &tc.Task}
+		if got := opts.timeoutForTask(pt); got != tc.Expected {
+			t.Errorf("%v: timeoutForTask got %v, want %v", tc.Name, got, tc.Expected)
+		}
+	}
+}
+
+func Test_ArgsForTask(t *testing.T) {
+	rs := &runSpec{
+		Targets: []string{"build", "test"},
+		Opts: &Opts{runOpts: runOpts{
+			passThroughArgs: []string{"--verbose"},
+			taskArgs: map[string][]string{
+				"test":             {"--coverage"},
+				"web#build":        {"--prod"},
+				"build@edge":       {"--edge"},
+				"docs#lint@strict": {"--strict"},
+			},
+		}},
+	}
+	pipelineArgs := []string{"--max-warnings", "0"}
+	edge := fs.TaskVariant{Name: "edge", Args: []string{"--target=edge"}, Task: "build"}
+	cases := []struct {
+		Name     string
+		Task     *nodes.PackageTask
+		Expected []string
+	}{
+		{"target", &nodes.PackageTask{TaskID: "docs#build", PackageName: "docs", Task: "build", TaskDefinition: &fs.TaskDefinition{Args: pipelineArgs}}, []string{"--max-warnings", "0", "--verbose"}},
+		{"task name", &nodes.PackageTask{TaskID: "docs#test", PackageName: "docs", Task: "test", TaskDefinition: &fs.TaskDefinition{Args: pipelineArgs}}, []string{"--coverage", "--verbose"}},
+		{"package task", &nodes.PackageTask{TaskID: "web#build", PackageName: "web", Task: "build", TaskDefinition: &fs.TaskDefinition{}}, []string{"--prod", "--verbose"}},
+		{"variant", &nodes.PackageTask{TaskID: "docs#build@edge", PackageName: "docs", Task: "build@edge", TaskDefinition: &fs.TaskDefinition{Variant: edge}}, []string{"--edge", "--target=edge", "--verbose"}},
+		{"package task of a variant", &nodes.PackageTask{TaskID: "web#build@edge", PackageName: "web", Task: "build@edge", TaskDefinition: &fs.TaskDefinition{Variant: edge}}, []string{"--prod", "--target=edge", "--verbose"}},
+		{"dependency", &nodes.PackageTask{TaskID: "docs#lint", PackageName: "docs", Task: "lint", TaskDefinition: &fs.TaskDefinition{Args: pipelineArgs}}, []string{"--max-warnings", "0"}},
+	}
+	for _, tc := range cases {
+		if got := rs.ArgsForTask(tc.Task); !reflect.DeepEqual(got, tc.Expected) {
+			t.Errorf("%v: ArgsForTask got %v, want %v", tc.Name, got, tc.Expected)
+		}
+	}
+}
+
+func Test_validateTaskArgs(t *testing.T) {
+	pipeline := fs.Pipeline{
+		"build":      fs.TaskDefinition{},
+		"build@edge": fs.TaskDefinition{Variant: fs.TaskVariant{Name: "edge", Task: "build"}},
+		"web#lint":   fs.TaskDefinition{},
+	}
+	valid := []string{"build", "build@edge", "web#build", "docs#build@edge", "lint", "web#lint"}
+	for _, key := range valid {
+		err := validateTaskArgs(pipeline, map[string][]string{key: {"--flag"}})
+		assert.NoError(t, err, key)
+	}
+	invalid := []string{"test", "build@node", "web#test", "lint@strict"}
+	for _, key := range invalid {
+		err := validateTaskArgs(pipeline, map[string][]string{key: {"--flag"}})
+		assert.ErrorContains(t, err, "task `"+key+"` not found", key)
+	}
+}
+
 func TestUsageText(t *testing.T) {
 	defaultCwd, err := fs.GetCwd()
 	if err != nil {
@@ -452,3 +769,62 @@ func TestUsageText(t *testing.T) {
 	usage := cmd.Help()
 	assert.NotEmpty(t, usage, "expected usage text")
 }
+
+func Test_packageGraph(t *testing.T) {
+	topoGraph := dag.AcyclicGraph{}
+	topoGraph.Add("app")
+	topoGraph.Add("lib")
+	topoGraph.Add("tool")
+	topoGraph.Add("___ROOT___")
+	topoGraph.Connect(dag.BasicEdge("app", "lib"))
+	topoGraph.Connect(dag.BasicEdge("lib", "___ROOT___"))
+	topoGraph.Connect(dag.BasicEdge("tool", "___ROOT___"))
+	g := &completeGraph{TopologicalGraph: topoGraph}
+
+	graph := g.packageGraph(util.SetFromStrings([]string{"app", "missing"}))
+	vertices := []string{}
+	for _, v := range graph.Vertices() {
+		vertices = append(vertices, dag.VertexName(v))
+	}
+	sort.Strings(vertices)
+	expected := []string{"___ROOT___", "app", "lib"}
+	if !reflect.DeepEqual(vertices, expected) {
+		t.Errorf("packageGraph vertices got %v, want %v", vertices, expected)
+	}
+	if len(graph.Edges()) != 2 {
+		t.Errorf("packageGraph got %v edges, want 2", len(graph.Edges()))
+	}
+}
+
+type nullRecorder struct{}
+
+func (nullRecorder) LogEvent(analytics.EventPayload) {}
+
+func TestCheckCache(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	cacheDir := repoRoot.Join("cache")
+	turboCache, err := cache.New(cache.Opts{Dir: cacheDir, SkipRemote: true}, &config.Config{Cwd: repoRoot}, nil, nullRecorder{}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, cacheDir.Join("cached-hash").MkdirAll())
+
+	tasks := []hashedTask{
+		{TaskID: "lib#build", Hash: "cached-hash", Cache: &cache.ArtifactStatus{}, restorable: true},
+		{TaskID: "app#build", Hash: "new-hash", Cache: &cache.ArtifactStatus{}, restorable: true},
+		{TaskID: "app#lint", Hash: "no-script-hash"},
+		// Such as with "cache": false or --force
+		{TaskID: "lib#test", Hash: "cached-hash", Cache: &cache.ArtifactStatus{}},
+	}
+	r := &run{ui: cli.NewMockUi()}
+	summary := r.checkCache(turboCache, tasks)
+
+	assert.Equal(t, &cache.ArtifactStatus{Local: true}, tasks[0].Cache)
+	assert.Equal(t, &cache.ArtifactStatus{}, tasks[1].Cache)
+	assert.Nil(t, tasks[2].Cache)
+	assert.Equal(t, &cache.ArtifactStatus{}, tasks[3].Cache)
+	assert.Equal(t, &dryRunCacheSummary{Hits: 1, Misses: 2}, summary)
+
+	// Without cache reads, no cache is checked
+	tasks = []hashedTask{{TaskID: "lib#build", Hash: "cached-hash", Cache: &cache.ArtifactStatus{}}}
+	summary = r.checkCache(nil, tasks)
+	assert.Equal(t, &dryRunCacheSummary{Misses: 1}, summary)
+}
@@ -0,0 +1,295 @@
+package run
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/process"
+	"github.com/vercel/turborepo/cli/internal/runcache"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// _runSummaryVersion changes whenever a field of the run summary is removed or
+// changes meaning. Adding fields does not change it.
+const _runSummaryVersion = "1"
+
+// The status of each task in a run summary
+const (
+	_taskStatusBuilt      = "built"
+	_taskStatusCached     = "cached"
+	_taskStatusResumed    = "resumed"
+	_taskStatusFailed     = "failed"
+	_taskStatusTimedOut   = "timedOut"
+	_taskStatusStopped    = "stopped"
+	_taskStatusSkipped    = "skipped"
+	_taskStatusNotStarted = "notStarted"
+)
+
+// The status of each task's cache lookup in a run summary
+const (
+	_cacheStatusHit  = "HIT"
+	_cacheStatusMiss = "MISS"
+)
+
+// runSummary is the record of a single run, which --summarize writes to .turbo/runs
+type runSummary struct {
+	mu sync.Mutex
+
+	Version      string    `json:"version"`
+	ID           string    `json:"id"`
+	TurboVersion string    `json:"turboVersion"`
+	StartedAt    time.Time `json:"startedAt"`
+	EndedAt      time.Time `json:"endedAt"`
+	ExitCode     int       `json:"exitCode"`
+	// Args are the command line arguments that turbo was invoked with
+	Args              []string           `json:"args"`
+	GlobalHashSummary *globalHashSummary `json:"globalHashSummary"`
+	Packages          []string           `json:"packages"`
+	Tasks             []*taskSummary     `json:"tasks"`
+}
+
+// globalHashSummary lists the inputs to the hash that is shared by every task
+type globalHashSummary struct {
+	GlobalHash           string                                `json:"globalHash"`
+	Files                map[turbopath.AnchoredUnixPath]string `json:"files"`
+	RootExternalDepsHash string                                `json:"rootExternalDepsHash"`
+	EnvVars              []string                              `json:"envVars"`
+}
+
+// taskSummary records what happened to a single task. Fields which aren't known,
+// because the task never started or finished, are null.
+type taskSummary struct {
+	TaskID    string            `json:"taskId"`
+	Task      string            `json:"task"`
+	Package   string            `json:"package"`
+	Hash      string            `json:"hash"`
+	Status    string            `json:"status"`
+	Cache     *taskCacheSummary `json:"cache"`
+	StartedAt *time.Time        `json:"startedAt"`
+	EndedAt   *time.Time        `json:"endedAt"`
+	// ExitCode is 0 for cache hits, since only successful runs are cached
+	ExitCode     *int     `json:"exitCode"`
+	Command      string   `json:"command"`
+	Outputs      []string `json:"outputs"`
+	LogFile      string   `json:"logFile"`
+	Directory    string   `json:"directory"`
+	Dependencies []string `json:"dependencies"`
+	Dependents   []string `json:"dependents"`
+	// EnvVars are the names of the environment variables included in the task's hash
+	EnvVars []string `json:"envVars"`
+}
+
+// taskCacheSummary describes whether a task's outputs were restored from the cache
+type taskCacheSummary struct {
+	Status string `json:"status"`
+	// Source is "LOCAL" or "REMOTE" for hits
+	Source string `json:"source,omitempty"`
+}
+
+func newRunSummary(startedAt time.Time, turboVersion string, g *completeGraph, rs *runSpec) *runSummary {
+	packages := rs.FilteredPkgs.UnsafeListOfStrings()
+	sort.Strings(packages)
+	globalHash := &globalHashSummary{
+		GlobalHash: g.GlobalHash,
+		Files:      map[turbopath.AnchoredUnixPath]string{},
+		EnvVars:    []string{},
+	}
+	if g.GlobalHashInputs != nil {
+		globalHash.Files = g.GlobalHashInputs.Files
+		globalHash.RootExternalDepsHash = g.GlobalHashInputs.RootExternalDepsHash
+		globalHash.EnvVars = append(globalHash.EnvVars, g.GlobalHashInputs.EnvVars...)
+	}
+	return &runSummary{
+		Version:           _runSummaryVersion,
+		ID:                fmt.Sprintf("%v-%v", startedAt.UTC().Format("20060102T150405Z"), uuid.New().String()[:8]),
+		TurboVersion:      turboVersion,
+		StartedAt:         startedAt,
+		Args:              os.Args[1:],
+		GlobalHashSummary: globalHash,
+		Packages:          packages,
+		Tasks:             []*taskSummary{},
+	}
+}
+
+// newTaskSummary fills in what is known about a task before it runs
+func newTaskSummary(pt *nodes.PackageTask, hash string, envVars []string) *taskSummary {
+	command, _ := pt.Command()
+	outputs := pt.TaskDefinition.Outputs
+	if outputs == nil {
+		outputs = []string{}
+	}
+	if envVars == nil {
+		envVars = []string{}
+	}
+	return &taskSummary{
+		TaskID:       pt.TaskID,
+		Task:         pt.Task,
+		Package:      pt.PackageName,
+		Hash:         hash,
+		Status:       _taskStatusNotStarted,
+		Command:      command,
+		Outputs:      outputs,
+		LogFile:      pt.RepoRelativeLogFile(),
+		Directory:    pt.Pkg.Dir,
+		Dependencies: []string{},
+		Dependents:   []string{},
+		EnvVars:      envVars,
+	}
+}
+
+// startTask records that a task has begun executing. The returned summary belongs
+// to the caller until the run is over.
+func (s *runSummary) startTask(pt *nodes.PackageTask, hash string, envVars []string, startedAt time.Time) *taskSummary {
+	ts := newTaskSummary(pt, hash, envVars)
+	ts.StartedAt = &startedAt
+	ts.Cache = &taskCacheSummary{Status: _cacheStatusMiss}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Tasks = append(s.Tasks, ts)
+	return ts
+}
+
+// cacheHit records that a task's outputs were restored from the cache
+func (ts *taskSummary) cacheHit(status runcache.ItemStatus) {
+	ts.Cache = &taskCacheSummary{Status: _cacheStatusHit, Source: status.Source}
+}
+
+// finish records the outcome of a task. err is the error that the task failed with, if any.
+func (ts *taskSummary) finish(status string, err error) {
+	endedAt := time.Now()
+	ts.EndedAt = &endedAt
+	ts.Status = status
+	switch status {
+	case _taskStatusBuilt, _taskStatusCached, _taskStatusResumed:
+		exitCode := 0
+		ts.ExitCode = &exitCode
+		if status == _taskStatusResumed {
+			// The cache wasn't consulted
+			ts.Cache = nil
+		}
+	case _taskStatusFailed:
+		exitErr := &process.ChildExit{}
+		if errors.As(err, &exitErr) {
+			exitCode := exitErr.ExitCode
+			ts.ExitCode = &exitCode
+		}
+	}
+}
+
+// updateLastRun adds the tasks which succeeded in this run to the record of earlier
+// runs, for --resume, and removes the tasks which started in this run but didn't
+// succeed, since their outputs may have changed. Tasks which didn't start in this
+// run, such as those outside of its scope, keep their record.
+func (s *runSummary) updateLastRun(lr *lastRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ts := range s.Tasks {
+		if ts.Hash == "" {
+			continue
+		}
+		switch ts.Status {
+		case _taskStatusBuilt, _taskStatusCached, _taskStatusResumed:
+			lr.Succeeded[ts.TaskID] = ts.Hash
+		default:
+			delete(lr.Succeeded, ts.TaskID)
+		}
+	}
+}
+
+// complete fills in the parts of the summary which are only known once the run is
+// over: the overall exit code, the relationships between tasks, and the tasks
+// which never started. Tasks without a script in their package are left out.
+func (s *runSummary) complete(ctx gocontext.Context, g *completeGraph, engine *core.Scheduler, skipped []string, exitCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.EndedAt = time.Now()
+	s.ExitCode = exitCode
+
+	started := make(map[string]*taskSummary, len(s.Tasks))
+	for _, ts := range s.Tasks {
+		started[ts.TaskID] = ts
+	}
+	wasSkipped := util.SetFromStrings(skipped)
+	tasks := []*taskSummary{}
+	for _, v := range engine.TaskGraph.Vertices() {
+		taskID := v.(string)
+		if strings.Contains(taskID, core.ROOT_NODE_NAME) {
+			continue
+		}
+		ts, ok := started[taskID]
+		if !ok {
+			_ = g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+				if _, ok := pt.Command(); !ok {
+					return nil
+				}
+				ts = newTaskSummary(pt, "", nil)
+				if wasSkipped.Includes(taskID) {
+					ts.Status = _taskStatusSkipped
+				}
+				return nil
+			})(taskID)
+			if ts == nil {
+				continue
+			}
+		}
+		ts.Dependencies = relatedTasks(engine.TaskGraph.Ancestors(taskID))
+		ts.Dependents = relatedTasks(engine.TaskGraph.Descendents(taskID))
+		tasks = append(tasks, ts)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].TaskID < tasks[j].TaskID
+	})
+	s.Tasks = tasks
+}
+
+// relatedTasks lists the task ids in a set of vertices, without the placeholder root nodes
+func relatedTasks(vertices dag.Set, err error) []string {
+	ids := []string{}
+	if err != nil {
+		return ids
+	}
+	for _, v := range vertices {
+		if id := v.(string); !strings.Contains(id, core.ROOT_NODE_NAME) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// save writes the summary to .turbo/runs/<id>.json under the repository root, and
+// returns the path it was written to
+func (s *runSummary) save(repoRoot fs.AbsolutePath) (fs.AbsolutePath, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var contents bytes.Buffer
+	encoder := json.NewEncoder(&contents)
+	// Commands often contain characters such as &, which are clearer unescaped
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return "", errors.Wrap(err, "failed to render run summary")
+	}
+	path := repoRoot.Join(".turbo", "runs", s.ID+".json")
+	if err := path.EnsureDir(); err != nil {
+		return "", errors.Wrap(err, "failed to create run summary directory")
+	}
+	if err := path.WriteFile(contents.Bytes(), 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write run summary")
+	}
+	return path, nil
+}
@@ -0,0 +1,128 @@
+package run
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/process"
+	"github.com/vercel/turborepo/cli/internal/runcache"
+	"github.com/vercel/turborepo/cli/internal/util"
+	"gotest.tools/v3/assert"
+)
+
+func Test_runSummary(t *testing.T) {
+	g := watchTestGraph()
+	g.Pipeline["build"] = fs.TaskDefinition{
+		Outputs:                 []string{"dist/**"},
+		TopologicalDependencies: []string{"build"},
+	}
+	for _, name := range []string{"app", "lib", "tool"} {
+		g.PackageInfos[name].Scripts = map[string]string{"build": "build " + name}
+	}
+	rs := &runSpec{
+		Targets:      []string{"build"},
+		FilteredPkgs: util.SetFromStrings([]string{"app", "lib", "tool"}),
+		Opts:         &Opts{},
+	}
+	engine, err := buildTaskGraph(&g.TopologicalGraph, g.Pipeline, rs)
+	assert.NilError(t, err, "buildTaskGraph")
+
+	summary := newRunSummary(time.Now(), "1.2.3", g, rs)
+	visit := func(taskID string, record func(pt *nodes.PackageTask)) {
+		err := g.getPackageTaskVisitor(gocontext.Background(), func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+			record(pt)
+			return nil
+		})(taskID)
+		assert.NilError(t, err, "visitor")
+	}
+	visit("tool#build", func(pt *nodes.PackageTask) {
+		ts := summary.startTask(pt, "tool-hash", []string{"TOOL_ENV"}, time.Now())
+		ts.cacheHit(runcache.ItemStatus{Hit: true, Source: "REMOTE"})
+		ts.finish(_taskStatusCached, nil)
+	})
+	visit("lib#build", func(pt *nodes.PackageTask) {
+		ts := summary.startTask(pt, "lib-hash", nil, time.Now())
+		ts.finish(_taskStatusFailed, &process.ChildExit{ExitCode: 2})
+	})
+	summary.complete(gocontext.Background(), g, engine, []string{"app#build"}, 2)
+
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	path, err := summary.save(repoRoot)
+	assert.NilError(t, err, "save")
+	assert.Equal(t, path, repoRoot.Join(".turbo", "runs", summary.ID+".json"))
+	contents, err := path.ReadFile()
+	assert.NilError(t, err, "ReadFile")
+
+	var saved struct {
+		Version  string   `json:"version"`
+		ExitCode int      `json:"exitCode"`
+		Packages []string `json:"packages"`
+		Tasks    []struct {
+			TaskID string `json:"taskId"`
+			Hash   string `json:"hash"`
+			Status string `json:"status"`
+			Cache  *struct {
+				Status string `json:"status"`
+				Source string `json:"source"`
+			} `json:"cache"`
+			ExitCode     *int     `json:"exitCode"`
+			Command      string   `json:"command"`
+			Dependencies []string `json:"dependencies"`
+			Dependents   []string `json:"dependents"`
+			EnvVars      []string `json:"envVars"`
+		} `json:"tasks"`
+	}
+	assert.NilError(t, json.Unmarshal(contents, &saved), "Unmarshal")
+	assert.Equal(t, saved.Version, _runSummaryVersion)
+	assert.Equal(t, saved.ExitCode, 2)
+	assert.DeepEqual(t, saved.Packages, []string{"app", "lib", "tool"})
+	assert.Equal(t, len(saved.Tasks), 3)
+
+	app, lib, tool := saved.Tasks[0], saved.Tasks[1], saved.Tasks[2]
+	assert.Equal(t, app.TaskID, "app#build")
+	assert.Equal(t, app.Status, _taskStatusSkipped)
+	assert.Assert(t, app.Cache == nil)
+	assert.Assert(t, app.ExitCode == nil)
+	assert.Equal(t, app.Command, "build app")
+	assert.DeepEqual(t, app.Dependencies, []string{"lib#build"})
+
+	assert.Equal(t, lib.Status, _taskStatusFailed)
+	assert.Equal(t, lib.Hash, "lib-hash")
+	assert.Equal(t, lib.Cache.Status, _cacheStatusMiss)
+	assert.Equal(t, *lib.ExitCode, 2)
+	assert.DeepEqual(t, lib.Dependents, []string{"app#build"})
+	assert.DeepEqual(t, lib.EnvVars, []string{})
+
+	assert.Equal(t, tool.Status, _taskStatusCached)
+	assert.Equal(t, tool.Cache.Status, _cacheStatusHit)
+	assert.Equal(t, tool.Cache.Source, "REMOTE")
+	assert.Equal(t, *tool.ExitCode, 0)
+	assert.DeepEqual(t, tool.EnvVars, []string{"TOOL_ENV"})
+}
+
+func Test_taskSummaryFinish(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Status   string
+		Err      error
+		ExitCode *int
+	}{
+		{"built", _taskStatusBuilt, nil, intPtr(0)},
+		{"failed with exit code", _taskStatusFailed, &process.ChildExit{ExitCode: 7}, intPtr(7)},
+		{"failed to start", _taskStatusFailed, errors.New("bad .env file"), nil},
+		{"timed out", _taskStatusTimedOut, &process.ChildTimeout{Timeout: time.Second}, nil},
+		{"stopped", _taskStatusStopped, nil, nil},
+	}
+	for _, tc := range cases {
+		ts := &taskSummary{}
+		ts.finish(tc.Status, tc.Err)
+		assert.Equal(t, ts.Status, tc.Status, tc.Name)
+		assert.Assert(t, ts.EndedAt != nil, tc.Name)
+		assert.DeepEqual(t, ts.ExitCode, tc.ExitCode)
+	}
+}
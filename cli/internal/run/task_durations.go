@@ -0,0 +1,80 @@
+package run
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+// _taskDurationsFile is stored in the cache directory, so that the history is
+// kept wherever the local cache is kept, including between CI runs.
+const _taskDurationsFile = "task-durations.json"
+
+// taskDurations is a history of how long each task took to execute, used to
+// schedule the longest chains of tasks first.
+type taskDurations struct {
+	mu   sync.Mutex
+	path fs.AbsolutePath
+	// durations are in milliseconds, keyed by task ID
+	durations map[string]int64
+	dirty     bool
+}
+
+// loadTaskDurations reads the history from the given cache directory. A missing or
+// unreadable history is treated as empty, since it only affects scheduling order.
+func loadTaskDurations(cacheDir fs.AbsolutePath) *taskDurations {
+	td := &taskDurations{
+		path:      cacheDir.Join(_taskDurationsFile),
+		durations: make(map[string]int64),
+	}
+	contents, err := td.path.ReadFile()
+	if err == nil {
+		if err := json.Unmarshal(contents, &td.durations); err != nil {
+			td.durations = make(map[string]int64)
+		}
+	}
+	return td
+}
+
+// Estimates returns the expected duration of each task with a history
+func (td *taskDurations) Estimates() map[string]time.Duration {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	estimates := make(map[string]time.Duration, len(td.durations))
+	for taskID, ms := range td.durations {
+		estimates[taskID] = time.Duration(ms) * time.Millisecond
+	}
+	return estimates
+}
+
+// Record adds a task execution to the history. Previous durations are averaged in,
+// so that one unusually slow or fast run doesn't throw off the estimate.
+func (td *taskDurations) Record(taskID string, duration time.Duration) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	ms := duration.Milliseconds()
+	if previous, ok := td.durations[taskID]; ok {
+		ms = (previous + ms) / 2
+	}
+	td.durations[taskID] = ms
+	td.dirty = true
+}
+
+// Save writes the history back to disk, if anything was recorded
+func (td *taskDurations) Save() error {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	if !td.dirty {
+		return nil
+	}
+	contents, err := json.Marshal(td.durations)
+	if err != nil {
+		return err
+	}
+	if err := td.path.EnsureDir(); err != nil {
+		return err
+	}
+	return td.path.WriteFile(contents, 0644)
+}
@@ -0,0 +1,35 @@
+package run
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+func Test_taskDurations(t *testing.T) {
+	cacheDir := fs.AbsolutePathFromUpstream(t.TempDir()).Join("cache")
+
+	durations := loadTaskDurations(cacheDir)
+	assert.DeepEqual(t, durations.Estimates(), map[string]time.Duration{})
+
+	durations.Record("a#build", 10*time.Second)
+	assert.NilError(t, durations.Save(), "Save")
+
+	durations = loadTaskDurations(cacheDir)
+	assert.DeepEqual(t, durations.Estimates(), map[string]time.Duration{"a#build": 10 * time.Second})
+
+	// new durations are averaged with the history
+	durations.Record("a#build", 20*time.Second)
+	assert.DeepEqual(t, durations.Estimates(), map[string]time.Duration{"a#build": 15 * time.Second})
+}
+
+func Test_taskDurationsCorrupt(t *testing.T) {
+	cacheDir := fs.AbsolutePathFromUpstream(t.TempDir())
+	err := cacheDir.Join(_taskDurationsFile).WriteFile([]byte("not json"), 0644)
+	assert.NilError(t, err, "WriteFile")
+
+	durations := loadTaskDurations(cacheDir)
+	assert.DeepEqual(t, durations.Estimates(), map[string]time.Duration{})
+}
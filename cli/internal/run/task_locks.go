@@ -0,0 +1,113 @@
+package run
+
+import (
+	gocontext "context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nightlyone/lockfile"
+	"github.com/pkg/errors"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// _taskLockPollInterval is how often a process checks whether the task that it is
+// waiting on has been finished by another process
+const _taskLockPollInterval = 250 * time.Millisecond
+
+// _taskLockMaxWait is how long a process waits on another process's task, if the
+// task has no timeout of its own, before executing it anyway
+const _taskLockMaxWait = 30 * time.Minute
+
+// errTaskLockWaitExceeded is returned by acquire when another process holds the lock
+// for longer than the caller is willing to wait
+var errTaskLockWaitExceeded = errors.New("gave up waiting for another turbo process to finish this task")
+
+// taskLocks coordinates the turbo processes running in a repository, so that only
+// one of them executes the task for a given hash at a time. The others wait, and
+// then restore the task's outputs from the cache. Each lock is a file under
+// .turbo/locks holding the pid of its owner, and locks whose owner has exited
+// without releasing them are taken over. Other files which the processes share,
+// such as the record of the last run, are locked the same way, under their own names.
+type taskLocks struct {
+	dir    fs.AbsolutePath
+	logger hclog.Logger
+
+	mu sync.Mutex
+	// held is the hashes that this process holds the lock for
+	held util.Set
+}
+
+func newTaskLocks(repoRoot fs.AbsolutePath, logger hclog.Logger) *taskLocks {
+	return &taskLocks{
+		dir:    repoRoot.Join(".turbo", "locks"),
+		logger: logger,
+		held:   make(util.Set),
+	}
+}
+
+// acquire takes the lock for hash, waiting for up to maxWait while another process
+// holds it, after which it returns errTaskLockWaitExceeded. onWait is called with the
+// pid of that process if acquire has to wait. The returned
+// function releases the lock, and must be called exactly once. Tasks in this process
+// which share a hash aren't coordinated, so acquire doesn't wait for them.
+func (l *taskLocks) acquire(ctx gocontext.Context, hash string, maxWait time.Duration, onWait func(pid int)) (func(), error) {
+	l.mu.Lock()
+	if l.held.Includes(hash) {
+		l.mu.Unlock()
+		return func() {}, nil
+	}
+	l.held.Add(hash)
+	l.mu.Unlock()
+	unheld := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.held.Delete(hash)
+	}
+
+	path := l.dir.Join(hash + ".lock")
+	if err := path.EnsureDir(); err != nil {
+		unheld()
+		return nil, errors.Wrap(err, "failed to create lock directory")
+	}
+	lock, err := lockfile.New(path.ToString())
+	if err != nil {
+		unheld()
+		return nil, err
+	}
+	waited := false
+	giveUp := time.After(maxWait)
+	for {
+		err := lock.TryLock()
+		if err == nil {
+			break
+		}
+		var temporary lockfile.TemporaryError
+		if !errors.As(err, &temporary) {
+			unheld()
+			return nil, errors.Wrapf(err, "failed to lock %v", path)
+		}
+		if !waited && errors.Is(err, lockfile.ErrBusy) {
+			waited = true
+			if owner, err := lock.GetOwner(); err == nil {
+				onWait(owner.Pid)
+			}
+		}
+		select {
+		case <-time.After(_taskLockPollInterval):
+		case <-giveUp:
+			unheld()
+			return nil, errTaskLockWaitExceeded
+		case <-ctx.Done():
+			unheld()
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			l.logger.Debug("failed to release task lock", "hash", hash, "error", err)
+		}
+		unheld()
+	}, nil
+}
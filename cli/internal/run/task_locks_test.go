@@ -0,0 +1,69 @@
+package run
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+func Test_taskLocks(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	locks := newTaskLocks(repoRoot, hclog.NewNullLogger())
+	noWait := func(pid int) {
+		t.Errorf("unexpectedly waited on pid %v", pid)
+	}
+	ctx := gocontext.Background()
+
+	release, err := locks.acquire(ctx, "some-hash", time.Minute, noWait)
+	assert.NilError(t, err, "acquire")
+	lockFile := repoRoot.Join(".turbo", "locks", "some-hash.lock")
+	assert.Assert(t, lockFile.FileExists())
+	// Tasks in the same process don't wait for each other
+	releaseAgain, err := locks.acquire(ctx, "some-hash", time.Minute, noWait)
+	assert.NilError(t, err, "acquire")
+	releaseAgain()
+	release()
+	assert.Assert(t, !lockFile.FileExists())
+
+	// A lock held by another live process is waited on until it is released
+	assert.NilError(t, lockFile.WriteFile([]byte(fmt.Sprintf("%d\n", os.Getppid())), 0644), "WriteFile")
+	waitedOn := 0
+	go func() {
+		time.Sleep(2 * _taskLockPollInterval)
+		_ = lockFile.Remove()
+	}()
+	release, err = locks.acquire(ctx, "some-hash", time.Minute, func(pid int) {
+		waitedOn = pid
+	})
+	assert.NilError(t, err, "acquire")
+	assert.Equal(t, waitedOn, os.Getppid())
+	release()
+
+	// A lock left behind by a process which has exited is taken over
+	cmd := exec.Command("go", "version")
+	assert.NilError(t, cmd.Run(), "Run")
+	assert.NilError(t, lockFile.WriteFile([]byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0644), "WriteFile")
+	release, err = locks.acquire(ctx, "some-hash", time.Minute, noWait)
+	assert.NilError(t, err, "acquire")
+	release()
+
+	// Waiting stops when the run is cancelled
+	assert.NilError(t, lockFile.WriteFile([]byte(fmt.Sprintf("%d\n", os.Getppid())), 0644), "WriteFile")
+	ctx, cancel := gocontext.WithTimeout(ctx, _taskLockPollInterval)
+	defer cancel()
+	_, err = locks.acquire(ctx, "some-hash", time.Minute, func(pid int) {})
+	assert.ErrorIs(t, err, gocontext.DeadlineExceeded)
+	assert.Assert(t, !locks.held.Includes("some-hash"))
+
+	// Waiting stops after maxWait
+	_, err = locks.acquire(gocontext.Background(), "some-hash", _taskLockPollInterval, func(pid int) {})
+	assert.ErrorIs(t, err, errTaskLockWaitExceeded)
+	assert.Assert(t, !locks.held.Includes("some-hash"))
+}
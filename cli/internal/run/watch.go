@@ -0,0 +1,510 @@
+package run
+
+import (
+	gocontext "context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pyr-sh/dag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/doublestar"
+	"github.com/vercel/turborepo/cli/internal/filewatcher"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/packagemanager"
+	"github.com/vercel/turborepo/cli/internal/process"
+	"github.com/vercel/turborepo/cli/internal/scope"
+	scope_filter "github.com/vercel/turborepo/cli/internal/scope/filter"
+	"github.com/vercel/turborepo/cli/internal/signals"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+)
+
+// _watchDebounce is how long the filesystem must be quiet before tasks re-run, so
+// that a burst of changes, such as a branch checkout, only causes a single run
+const _watchDebounce = 200 * time.Millisecond
+
+// WatchCommand is a Command implementation that re-runs tasks when files change
+type WatchCommand struct {
+	Config        *config.Config
+	UI            *cli.ColoredUi
+	SignalWatcher *signals.Watcher
+}
+
+var _watchCmdLong = `
+Run tasks across projects in your monorepo, then re-run them as files change.
+
+When files in a package change, the tasks in that package and in every package
+that depends on it are re-run. Changes to files outside of any package re-run
+every task. If files change while tasks are running, the running tasks are
+interrupted and started again with the latest changes.
+
+Arguments passed after '--' will be passed through to the named tasks.
+`
+
+func getWatchCmd(config *config.Config, output cli.Ui, signalWatcher *signals.Watcher) *cobra.Command {
+	var opts *Opts
+	var flags *pflag.FlagSet
+	cmd := &cobra.Command{
+		Use:                   "turbo watch <task> [...<task>] [<flags>] -- <args passed to tasks>",
+		Short:                 "Re-run tasks across projects in your monorepo when files change",
+		Long:                  _watchCmdLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, passThroughArgs := parseTasksAndPassthroughArgs(args, flags)
+			if len(tasks) == 0 {
+				return errors.New("at least one task must be specified")
+			}
+			if err := opts.runOpts.validate(); err != nil {
+				return err
+			}
+			if opts.runOpts.dryRun || opts.runOpts.graphDot || opts.runOpts.graphFile != "" {
+				return errors.New("--dry-run and --graph cannot be used with turbo watch")
+			}
+			opts.runOpts.passThroughArgs = passThroughArgs
+			w := &watch{
+				opts:          opts,
+				config:        config,
+				ui:            output,
+				signalWatcher: signalWatcher,
+			}
+			return w.watch(cmd.Context(), tasks)
+		},
+	}
+	flags = cmd.Flags()
+	opts = optsFromFlags(flags, config)
+	return cmd
+}
+
+// Synopsis of watch command
+func (c *WatchCommand) Synopsis() string {
+	cmd := getWatchCmd(c.Config, c.UI, c.SignalWatcher)
+	return cmd.Short
+}
+
+// Help returns information about the `watch` command
+func (c *WatchCommand) Help() string {
+	cmd := getWatchCmd(c.Config, c.UI, c.SignalWatcher)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run executes tasks in the monorepo, and again whenever files change
+func (c *WatchCommand) Run(args []string) int {
+	cmd := getWatchCmd(c.Config, c.UI, c.SignalWatcher)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.logError(c.Config.Logger, "", err)
+		return 1
+	}
+	return 0
+}
+
+// logError logs an error and outputs it to the UI.
+func (c *WatchCommand) logError(log hclog.Logger, prefix string, err error) {
+	log.Error(prefix, "error", err)
+
+	if prefix != "" {
+		prefix += ": "
+	}
+
+	c.UI.Error(fmt.Sprintf("%s%s%s", ui.ERROR_PREFIX, prefix, color.RedString(" %v", err)))
+}
+
+type watch struct {
+	opts          *Opts
+	config        *config.Config
+	ui            cli.Ui
+	signalWatcher *signals.Watcher
+
+	mu sync.Mutex
+	// inFlight is the run that is currently executing, if any
+	inFlight *watchRun
+}
+
+// watchRun is a single execution of the tasks, which can be interrupted
+type watchRun struct {
+	packages  util.Set
+	cancel    gocontext.CancelFunc
+	processes *process.Manager
+	done      chan error
+}
+
+// stop interrupts the run and waits for it to finish
+func (wr *watchRun) stop() {
+	wr.cancel()
+	wr.processes.Close()
+	<-wr.done
+}
+
+func (w *watch) watch(ctx gocontext.Context, targets []string) error {
+	ctx, cancel := gocontext.WithCancel(ctx)
+	defer cancel()
+	w.signalWatcher.AddOnClose(func() {
+		cancel()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.inFlight != nil {
+			w.inFlight.processes.Close()
+		}
+	})
+	// run is only used for reading configuration, each execution gets its own processes
+	r := &run{
+		opts:   w.opts,
+		config: w.config,
+		ui:     w.ui,
+	}
+	g, rs, packageManager, err := r.prepare(targets)
+	if err != nil {
+		return err
+	}
+
+	backend, err := filewatcher.GetPlatformSpecificBackend(w.config.Logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to start watching files")
+	}
+	fileWatcher := filewatcher.New(w.config.Logger.Named("filewatcher"), w.config.Cwd, backend)
+	changes := newChangeDebouncer(w.config.Cwd, w.config.Logger.Named("watch"))
+	fileWatcher.AddClient(changes)
+	if err := fileWatcher.Start(); err != nil {
+		return errors.Wrapf(err, "watching %v", w.config.Cwd)
+	}
+	defer func() { _ = fileWatcher.Close() }()
+	batches := changes.batches(ctx, _watchDebounce)
+
+	pending := rs.FilteredPkgs.Copy()
+	for {
+		w.mu.Lock()
+		if w.inFlight == nil && pending.Len() > 0 {
+			w.inFlight = w.start(ctx, g, rs, packageManager, pending)
+			pending = make(util.Set)
+		}
+		inFlight := w.inFlight
+		w.mu.Unlock()
+		var done chan error
+		if inFlight != nil {
+			done = inFlight.done
+		}
+
+		select {
+		case <-ctx.Done():
+			if inFlight != nil {
+				inFlight.stop()
+			}
+			return nil
+		case err := <-done:
+			// Failed tasks have already been reported
+			exitErr := &process.ChildExit{}
+			if err != nil && !errors.As(err, &exitErr) {
+				r.logWarning("", err)
+			}
+			w.setInFlight(nil)
+			w.ui.Output(ui.Dim("• Watching for changes..."))
+		case changedFiles, ok := <-batches:
+			if !ok {
+				if ctx.Err() != nil {
+					batches = nil
+					continue
+				}
+				return errors.New("file watching stopped unexpectedly")
+			}
+			if needsReload(changedFiles) {
+				// The package graph or pipeline may have changed, so start over
+				reloadedGraph, reloadedSpec, reloadedPackageManager, err := r.prepare(targets)
+				if err != nil {
+					r.logWarning("failed to reload configuration, keeping the previous configuration", err)
+				} else {
+					g, rs, packageManager = reloadedGraph, reloadedSpec, reloadedPackageManager
+					pending = rs.FilteredPkgs.Copy()
+				}
+			}
+			affected, err := affectedPackages(g, &rs.Opts.scopeOpts, rs.FilteredPkgs, changedFiles)
+			if err != nil {
+				r.logWarning("failed to determine affected packages", err)
+				continue
+			}
+			if affected.Len() == 0 && pending.Len() == 0 {
+				continue
+			}
+			if inFlight != nil {
+				// The running tasks are stale, start them again along with the new changes
+				inFlight.stop()
+				w.setInFlight(nil)
+				pending = union(pending, inFlight.packages)
+			}
+			pending = union(pending, affected)
+		}
+	}
+}
+
+func (w *watch) setInFlight(wr *watchRun) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight = wr
+}
+
+// start executes the targets in the given packages in the background
+func (w *watch) start(ctx gocontext.Context, g *completeGraph, rs *runSpec, packageManager *packagemanager.PackageManager, packages util.Set) *watchRun {
+	runCtx, cancel := gocontext.WithCancel(ctx)
+	processes := process.NewManager(w.config.Logger.Named("processes"))
+	r := &run{
+		opts:      w.opts,
+		config:    w.config,
+		ui:        w.ui,
+		processes: processes,
+	}
+	runSpec := &runSpec{
+		Targets:      rs.Targets,
+		FilteredPkgs: packages,
+		Opts:         rs.Opts,
+	}
+	wr := &watchRun{
+		packages:  packages,
+		cancel:    cancel,
+		processes: processes,
+		done:      make(chan error, 1),
+	}
+	go func() {
+		wr.done <- r.runOperation(runCtx, g.copyForRun(), runSpec, packageManager, time.Now())
+	}()
+	return wr
+}
+
+// copyForRun returns a copy of the graph, since running in parallel removes edges from
+// the package graph, which are still needed to find the dependents of changed packages
+func (g *completeGraph) copyForRun() *completeGraph {
+	topoGraph := dag.AcyclicGraph{}
+	for _, v := range g.TopologicalGraph.Vertices() {
+		topoGraph.Add(v)
+	}
+	for _, e := range g.TopologicalGraph.Edges() {
+		topoGraph.Connect(e)
+	}
+	return &completeGraph{
+		TopologicalGraph: topoGraph,
+		Pipeline:         g.Pipeline,
+		PackageInfos:     g.PackageInfos,
+		GlobalHash:       g.GlobalHash,
+		GlobalHashInputs: g.GlobalHashInputs,
+		RootNode:         g.RootNode,
+		Frameworks:       g.Frameworks,
+	}
+}
+
+func union(a util.Set, b util.Set) util.Set {
+	result := a.Copy()
+	for item := range b {
+		result.Add(item)
+	}
+	return result
+}
+
+// needsReload returns true if any of the changed files configure the package graph or pipeline
+func needsReload(changedFiles []string) bool {
+	for _, file := range changedFiles {
+		if file == "turbo.json" || filepath.Base(file) == "package.json" {
+			return true
+		}
+	}
+	return false
+}
+
+// affectedPackages returns the packages in scope whose tasks should re-run after the
+// given repo-relative files changed: the packages containing them, and their dependents.
+// Changes to task outputs, which are written by the tasks themselves, are ignored.
+func affectedPackages(g *completeGraph, opts *scope.Opts, inScope util.Set, changedFiles []string) (util.Set, error) {
+	var inputs []string
+	for _, file := range changedFiles {
+		isOutput, err := isTaskOutput(g, file)
+		if err != nil {
+			return nil, err
+		}
+		if !isOutput {
+			inputs = append(inputs, file)
+		}
+	}
+	if len(inputs) == 0 {
+		return make(util.Set), nil
+	}
+	changedPkgs, err := scope.ChangedPackages(opts, inputs, g.PackageInfos)
+	if err != nil {
+		return nil, err
+	}
+	if changedPkgs.Includes(util.RootPkgName) {
+		// Files outside of packages, such as lockfiles and shared configuration,
+		// could affect anything
+		return inScope.Copy(), nil
+	}
+	patterns := make([]string, 0, changedPkgs.Len())
+	for _, pkg := range changedPkgs.UnsafeListOfStrings() {
+		// Select the package and everything that depends on it
+		patterns = append(patterns, "..."+pkg)
+	}
+	resolver := &scope_filter.Resolver{
+		Graph:        &g.TopologicalGraph,
+		PackageInfos: g.PackageInfos,
+	}
+	affected, err := resolver.GetPackagesFromPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return affected.Intersection(inScope), nil
+}
+
+// isTaskOutput returns true if the repo-relative file is written by running tasks,
+// either as a declared output, or a log file, or an installed dependency
+func isTaskOutput(g *completeGraph, file string) (bool, error) {
+	unixFile := filepath.ToSlash(file)
+	if strings.Contains("/"+unixFile+"/", "/node_modules/") || strings.Contains("/"+unixFile+"/", "/.turbo/") {
+		return true, nil
+	}
+	for pkgName, pkg := range g.PackageInfos {
+		pkgDir := filepath.ToSlash(pkg.Dir)
+		if pkgName == util.RootPkgName || pkgDir == "" || pkgDir == "." {
+			continue
+		}
+		if !strings.HasPrefix(unixFile, pkgDir+"/") {
+			continue
+		}
+		pkgFile := strings.TrimPrefix(unixFile, pkgDir+"/")
+		for taskID, taskDefinition := range g.Pipeline {
+			if util.IsPackageTask(taskID) {
+				if taskPkg, _ := util.GetPackageTaskFromId(taskID); taskPkg != pkgName {
+					continue
+				}
+			}
+			for _, output := range taskDefinition.Outputs {
+				match, err := doublestar.Match(output, pkgFile)
+				if err != nil {
+					return false, fmt.Errorf("invalid output glob %v for %v: %w", output, taskID, err)
+				}
+				if match {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// changeDebouncer collects file changes from the file watcher, and hands them out
+// in batches once the filesystem has been quiet for a while
+type changeDebouncer struct {
+	repoRoot string
+	logger   hclog.Logger
+
+	mu      sync.Mutex
+	changed map[string]struct{}
+	// notify has a buffer of one, so that it never blocks the file watcher
+	notify    chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ filewatcher.FileWatchClient = &changeDebouncer{}
+
+func newChangeDebouncer(repoRoot fs.AbsolutePath, logger hclog.Logger) *changeDebouncer {
+	return &changeDebouncer{
+		repoRoot: repoRoot.ToString(),
+		logger:   logger,
+		changed:  make(map[string]struct{}),
+		notify:   make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+}
+
+// OnFileWatchEvent implements FileWatchClient.OnFileWatchEvent
+func (c *changeDebouncer) OnFileWatchEvent(ev filewatcher.Event) {
+	file, err := filepath.Rel(c.repoRoot, ev.Path.ToString())
+	if err != nil {
+		c.logger.Debug(fmt.Sprintf("ignoring change outside of the repository: %v", ev.Path))
+		return
+	}
+	c.mu.Lock()
+	c.changed[file] = struct{}{}
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// OnFileWatchError implements FileWatchClient.OnFileWatchError
+func (c *changeDebouncer) OnFileWatchError(err error) {
+	c.logger.Warn(fmt.Sprintf("file watching error: %v", err))
+}
+
+// OnFileWatchClosed implements FileWatchClient.OnFileWatchClosed
+func (c *changeDebouncer) OnFileWatchClosed() {
+	c.closeOnce.Do(func() { close(c.closed) })
+}
+
+// batches returns a channel of the repo-relative paths that changed, sorted. Each batch
+// is sent once no changes have happened for the given delay. The channel is closed
+// when file watching stops or ctx is done.
+func (c *changeDebouncer) batches(ctx gocontext.Context, delay time.Duration) <-chan []string {
+	batches := make(chan []string)
+	go func() {
+		defer close(batches)
+		for {
+			// wait for the first change
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			case <-c.notify:
+			}
+			// then wait for the changes to settle
+			timer := time.NewTimer(delay)
+		settle:
+			for {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-c.closed:
+					timer.Stop()
+					return
+				case <-c.notify:
+					if !timer.Stop() {
+						<-timer.C
+					}
+					timer.Reset(delay)
+				case <-timer.C:
+					break settle
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case batches <- c.take():
+			}
+		}
+	}()
+	return batches
+}
+
+// take returns and forgets the files which have changed
+func (c *changeDebouncer) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files := make([]string, 0, len(c.changed))
+	for file := range c.changed {
+		files = append(files, file)
+	}
+	c.changed = make(map[string]struct{})
+	sort.Strings(files)
+	return files
+}
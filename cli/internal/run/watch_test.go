@@ -0,0 +1,150 @@
+package run
+
+import (
+	gocontext "context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pyr-sh/dag"
+	"github.com/vercel/turborepo/cli/internal/filewatcher"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/scope"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func watchTestGraph() *completeGraph {
+	// app depends on lib, tool is independent
+	topoGraph := dag.AcyclicGraph{}
+	topoGraph.Add("app")
+	topoGraph.Add("lib")
+	topoGraph.Add("tool")
+	topoGraph.Connect(dag.BasicEdge("app", "lib"))
+	return &completeGraph{
+		TopologicalGraph: topoGraph,
+		Pipeline: fs.Pipeline{
+			"build": {
+				Outputs: []string{"dist/**"},
+			},
+			"tool#build": {
+				Outputs: []string{"bin/**"},
+			},
+		},
+		PackageInfos: map[interface{}]*fs.PackageJSON{
+			util.RootPkgName: {Name: util.RootPkgName, Dir: "."},
+			"app":            {Name: "app", Dir: filepath.FromSlash("apps/app")},
+			"lib":            {Name: "lib", Dir: filepath.FromSlash("packages/lib")},
+			"tool":           {Name: "tool", Dir: filepath.FromSlash("packages/tool")},
+		},
+	}
+}
+
+func Test_affectedPackages(t *testing.T) {
+	g := watchTestGraph()
+	inScope := util.SetFromStrings([]string{"app", "lib", "tool"})
+	cases := []struct {
+		Name         string
+		ChangedFiles []string
+		Expected     []string
+	}{
+		{
+			"dependents are affected",
+			[]string{"packages/lib/src/index.ts"},
+			[]string{"app", "lib"},
+		},
+		{
+			"leaf package",
+			[]string{"apps/app/src/index.ts"},
+			[]string{"app"},
+		},
+		{
+			"outputs are ignored",
+			[]string{"packages/lib/dist/index.js", "apps/app/.turbo/turbo-build.log"},
+			[]string{},
+		},
+		{
+			"package-specific outputs",
+			[]string{"packages/tool/bin/tool", "packages/lib/bin/lib"},
+			[]string{"app", "lib"},
+		},
+		{
+			"root files affect everything",
+			[]string{"tsconfig.base.json"},
+			[]string{"app", "lib", "tool"},
+		},
+	}
+	for _, tc := range cases {
+		changedFiles := make([]string, len(tc.ChangedFiles))
+		for i, file := range tc.ChangedFiles {
+			changedFiles[i] = filepath.FromSlash(file)
+		}
+		affected, err := affectedPackages(g, &scope.Opts{}, inScope, changedFiles)
+		if err != nil {
+			t.Fatalf("%v: affectedPackages: %v", tc.Name, err)
+		}
+		got := affected.UnsafeListOfStrings()
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.Expected) {
+			t.Errorf("%v: affectedPackages got %v, want %v", tc.Name, got, tc.Expected)
+		}
+	}
+}
+
+func Test_affectedPackagesInScope(t *testing.T) {
+	g := watchTestGraph()
+	inScope := util.SetFromStrings([]string{"lib"})
+	affected, err := affectedPackages(g, &scope.Opts{}, inScope, []string{filepath.FromSlash("packages/lib/src/index.ts")})
+	if err != nil {
+		t.Fatalf("affectedPackages: %v", err)
+	}
+	if got := affected.UnsafeListOfStrings(); !reflect.DeepEqual(got, []string{"lib"}) {
+		t.Errorf("affectedPackages got %v, want [lib]", got)
+	}
+}
+
+func Test_needsReload(t *testing.T) {
+	if !needsReload([]string{"turbo.json"}) {
+		t.Error("expected turbo.json to need a reload")
+	}
+	if !needsReload([]string{filepath.FromSlash("packages/lib/package.json")}) {
+		t.Error("expected package.json to need a reload")
+	}
+	if needsReload([]string{filepath.FromSlash("packages/lib/src/turbo.json")}) {
+		t.Error("expected a nested turbo.json not to need a reload")
+	}
+}
+
+func TestChangeDebouncer(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	c := newChangeDebouncer(repoRoot, hclog.NewNullLogger())
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+	batches := c.batches(ctx, 50*time.Millisecond)
+
+	c.OnFileWatchEvent(filewatcher.Event{Path: repoRoot.Join("b.txt"), EventType: filewatcher.FileModified})
+	c.OnFileWatchEvent(filewatcher.Event{Path: repoRoot.Join("a.txt"), EventType: filewatcher.FileAdded})
+	c.OnFileWatchEvent(filewatcher.Event{Path: repoRoot.Join("b.txt"), EventType: filewatcher.FileModified})
+
+	select {
+	case batch := <-batches:
+		expected := []string{"a.txt", "b.txt"}
+		if !reflect.DeepEqual(batch, expected) {
+			t.Errorf("batch got %v, want %v", batch, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a batch of changes")
+	}
+
+	c.OnFileWatchClosed()
+	select {
+	case _, ok := <-batches:
+		if ok {
+			t.Error("expected no more batches after file watching closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for batches to close")
+	}
+}
@@ -147,14 +147,30 @@ type TaskCache struct {
 	LogFileName       fs.AbsolutePath
 }
 
-// RestoreOutputs attempts to restore output for the corresponding task from the cache. Returns true
-// if successful.
-func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi, logger hclog.Logger) (bool, error) {
+// OutputMode returns how much of the task's output should be shown
+func (tc TaskCache) OutputMode() util.TaskOutputMode {
+	return tc.taskOutputMode
+}
+
+// ItemStatus describes whether a task's outputs were restored from the cache, and if so,
+// which cache they came from
+type ItemStatus struct {
+	Hit bool
+	// Source is cache.CacheSourceFS or cache.CacheSourceRemote for hits, or "" if unknown
+	Source string
+	// Duration is how long the task took to execute when its outputs were cached,
+	// in milliseconds, or 0 if the outputs weren't fetched
+	Duration int
+}
+
+// RestoreOutputs attempts to restore output for the corresponding task from the cache. The
+// returned status has Hit set if successful.
+func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi, logger hclog.Logger) (ItemStatus, error) {
 	if tc.cachingDisabled || tc.rc.readsDisabled {
 		if tc.taskOutputMode != util.NoTaskOutput {
 			terminal.Output(fmt.Sprintf("cache bypass, force executing %s", ui.Dim(tc.hash)))
 		}
-		return false, nil
+		return ItemStatus{}, nil
 	}
 	changedOutputGlobs, err := tc.rc.outputWatcher.GetChangedOutputs(ctx, tc.hash, tc.repoRelativeGlobs)
 	if err != nil {
@@ -163,18 +179,23 @@ func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi
 		changedOutputGlobs = tc.repoRelativeGlobs
 	}
 	hasChangedOutputs := len(changedOutputGlobs) > 0
+	// When the outputs on disk are already what the cache holds, nothing needs to be
+	// fetched, which amounts to a hit in the local cache
+	status := ItemStatus{Hit: true, Source: cache.CacheSourceFS}
 	if hasChangedOutputs {
 		// Note that we currently don't use the output globs when restoring, but we could in the
 		// future to avoid doing unnecessary file I/O
-		hit, _, _, err := tc.rc.cache.Fetch(tc.rc.repoRoot.ToString(), tc.hash, changedOutputGlobs)
+		hit, duration, source, err := cache.FetchWithSource(tc.rc.cache, tc.rc.repoRoot.ToString(), tc.hash, changedOutputGlobs)
 		if err != nil {
-			return false, err
+			return ItemStatus{}, err
 		} else if !hit {
 			if tc.taskOutputMode != util.NoTaskOutput {
 				terminal.Output(fmt.Sprintf("cache miss, executing %s", ui.Dim(tc.hash)))
 			}
-			return false, nil
+			return ItemStatus{}, nil
 		}
+		status.Source = source
+		status.Duration = duration
 		if err := tc.rc.outputWatcher.NotifyOutputsWritten(ctx, tc.hash, tc.repoRelativeGlobs); err != nil {
 			// Don't fail the whole operation just because we failed to watch the outputs
 			logger.Warn(fmt.Sprintf("Failed to mark outputs as cached for %v: %v", tc.pt.TaskID, err))
@@ -200,7 +221,7 @@ func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi
 	default:
 		// NoLogs, do not output anything
 	}
-	return true, nil
+	return status, nil
 }
 
 // nopWriteCloser is modeled after io.NopCloser, which is for Readers
@@ -223,10 +244,15 @@ func (fwc *fileWriterCloser) Close() error {
 	return fwc.file.Close()
 }
 
+// WritesLog returns whether OutputWriter writes the task's output to its log file
+func (tc TaskCache) WritesLog() bool {
+	return !tc.cachingDisabled && !tc.rc.writesDisabled
+}
+
 // OutputWriter creates a sink suitable for handling the output of the command associated
 // with this task.
 func (tc TaskCache) OutputWriter() (io.WriteCloser, error) {
-	if tc.cachingDisabled || tc.rc.writesDisabled {
+	if !tc.WritesLog() {
 		return nopWriteCloser{os.Stdout}, nil
 	}
 	// Setup log file
@@ -261,8 +287,16 @@ func (tc TaskCache) OutputWriter() (io.WriteCloser, error) {
 
 var _emptyIgnore []string
 
-// SaveOutputs is responsible for saving the outputs of task to the cache, after the task has completed
-func (tc TaskCache) SaveOutputs(ctx context.Context, logger hclog.Logger, terminal cli.Ui, duration int) error {
+// SaveOutputs is responsible for saving the outputs of task to the cache, after the task has completed.
+// saved is called once the outputs have been written to the cache, which may be after
+// SaveOutputs returns, or straight away if nothing is saved.
+func (tc TaskCache) SaveOutputs(ctx context.Context, logger hclog.Logger, terminal cli.Ui, duration int, saved func()) error {
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			saved()
+		}
+	}()
 	if tc.cachingDisabled || tc.rc.writesDisabled {
 		return nil
 	}
@@ -286,7 +320,8 @@ func (tc TaskCache) SaveOutputs(ctx context.Context, logger hclog.Logger, termin
 		relativePaths[index] = relativePath
 	}
 
-	if err = tc.rc.cache.Put(tc.pt.Pkg.Dir, tc.hash, duration, relativePaths); err != nil {
+	handedOff = true
+	if err = cache.PutThen(tc.rc.cache, tc.pt.Pkg.Dir, tc.hash, duration, relativePaths, saved); err != nil {
 		return err
 	}
 	err = tc.rc.outputWatcher.NotifyOutputsWritten(ctx, tc.hash, tc.repoRelativeGlobs)
@@ -299,6 +334,46 @@ func (tc TaskCache) SaveOutputs(ctx context.Context, logger hclog.Logger, termin
 	return nil
 }
 
+// OutputsHash returns a hash of the contents of the files currently matching this task's outputs.
+// The task's log file is excluded, since it embeds the task hash. If the task has no outputs,
+// or none of them exist, the hash is empty, since there is nothing for dependents to depend on
+// besides the task itself.
+func (tc TaskCache) OutputsHash() (string, error) {
+	logFile, err := tc.rc.repoRoot.RelativePathString(tc.LogFileName.ToString())
+	if err != nil {
+		return "", err
+	}
+	outputGlobs := make([]string, 0, len(tc.repoRelativeGlobs))
+	for _, glob := range tc.repoRelativeGlobs {
+		if glob != logFile {
+			outputGlobs = append(outputGlobs, glob)
+		}
+	}
+	if len(outputGlobs) == 0 {
+		return "", nil
+	}
+	files, err := globby.GlobFiles(tc.rc.repoRoot.ToStringDuringMigration(), outputGlobs, _emptyIgnore)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+	fileHashes := make(map[string]string, len(files))
+	for _, file := range files {
+		relativePath, err := tc.rc.repoRoot.RelativePathString(file)
+		if err != nil {
+			return "", err
+		}
+		hash, err := fs.GitLikeHashFile(file)
+		if err != nil {
+			return "", fmt.Errorf("could not hash output %v: %w", relativePath, err)
+		}
+		fileHashes[filepath.ToSlash(relativePath)] = hash
+	}
+	return fs.HashObject(fileHashes)
+}
+
 // TaskCache returns a TaskCache instance, providing an interface to the underlying cache specific
 // to this run and the given PackageTask
 func (rc *RunCache) TaskCache(pt *nodes.PackageTask, hash string) TaskCache {
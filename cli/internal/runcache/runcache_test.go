@@ -0,0 +1,98 @@
+package runcache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	"github.com/vercel/turborepo/cli/internal/analytics"
+	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/colorcache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"gotest.tools/v3/assert"
+)
+
+func TestOutputsHash(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	rc := New(nil, repoRoot, Opts{}, colorcache.New())
+	pkgDir := filepath.Join("packages", "lib")
+	pt := &nodes.PackageTask{
+		TaskID:         "lib#build",
+		Task:           "build",
+		PackageName:    "lib",
+		Pkg:            &fs.PackageJSON{Name: "lib", Dir: pkgDir},
+		TaskDefinition: &fs.TaskDefinition{Outputs: []string{"dist/**"}},
+	}
+	writeFile := func(path string, contents string) {
+		file := repoRoot.Join(pkgDir, path)
+		assert.NilError(t, file.EnsureDir(), "EnsureDir")
+		assert.NilError(t, file.WriteFile([]byte(contents), 0644), "WriteFile")
+	}
+	taskCache := rc.TaskCache(pt, "some-hash")
+
+	// Without any output files, dependents fall back to the task hash
+	writeFile(".turbo/turbo-build.log", "lib:build: cache hit, replaying output some-hash\n")
+	hash, err := taskCache.OutputsHash()
+	assert.NilError(t, err, "OutputsHash")
+	assert.Equal(t, hash, "")
+
+	writeFile("dist/index.js", "module.exports = 1")
+	first, err := taskCache.OutputsHash()
+	assert.NilError(t, err, "OutputsHash")
+	assert.Assert(t, first != "")
+
+	// The log file embeds the task hash, so it isn't part of the outputs hash
+	writeFile(".turbo/turbo-build.log", "lib:build: cache hit, replaying output other-hash\n")
+	hash, err = rc.TaskCache(pt, "other-hash").OutputsHash()
+	assert.NilError(t, err, "OutputsHash")
+	assert.Equal(t, hash, first)
+
+	writeFile("dist/index.js", "module.exports = 2")
+	hash, err = taskCache.OutputsHash()
+	assert.NilError(t, err, "OutputsHash")
+	assert.Assert(t, hash != first)
+
+	// A task without outputs, other than its log file, also falls back
+	pt.TaskDefinition = &fs.TaskDefinition{}
+	hash, err = rc.TaskCache(pt, "some-hash").OutputsHash()
+	assert.NilError(t, err, "OutputsHash")
+	assert.Equal(t, hash, "")
+}
+
+type nullRecorder struct{}
+
+func (nullRecorder) LogEvent(analytics.EventPayload) {}
+
+func TestRestoreOutputsDuration(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	cacheDir := repoRoot.Join("cache")
+	turboCache, err := cache.New(cache.Opts{Dir: cacheDir, SkipRemote: true}, &config.Config{Cwd: repoRoot}, nil, nullRecorder{}, nil)
+	assert.NilError(t, err, "New")
+	pt := &nodes.PackageTask{
+		TaskID:         "lib#build",
+		Task:           "build",
+		PackageName:    "lib",
+		Pkg:            &fs.PackageJSON{Name: "lib", Dir: filepath.Join("packages", "lib")},
+		TaskDefinition: &fs.TaskDefinition{Outputs: []string{"dist/**"}, ShouldCache: true},
+	}
+	artifact := cacheDir.Join("some-hash", "packages", "lib", "dist", "index.js")
+	assert.NilError(t, artifact.EnsureDir(), "EnsureDir")
+	assert.NilError(t, artifact.WriteFile([]byte("module.exports = 1"), 0644), "WriteFile")
+	assert.NilError(t, cache.WriteCacheMetaFile(cacheDir.Join("some-hash-meta.json").ToString(), &cache.CacheMetadata{Hash: "some-hash", Duration: 1234}), "WriteCacheMetaFile")
+
+	rc := New(turboCache, repoRoot, Opts{}, colorcache.New())
+	terminal := &cli.PrefixedUi{Ui: cli.NewMockUi()}
+	status, err := rc.TaskCache(pt, "some-hash").RestoreOutputs(context.Background(), terminal, hclog.NewNullLogger())
+	assert.NilError(t, err, "RestoreOutputs")
+	assert.Assert(t, status.Hit)
+	assert.Equal(t, status.Duration, 1234)
+
+	status, err = rc.TaskCache(pt, "other-hash").RestoreOutputs(context.Background(), terminal, hclog.NewNullLogger())
+	assert.NilError(t, err, "RestoreOutputs")
+	assert.Assert(t, !status.Hit)
+	assert.Equal(t, status.Duration, 0)
+}
@@ -151,22 +151,29 @@ func (o *Opts) getPackageChangeFunc(scm scm.SCM, cwd string, packageInfos map[in
 			}
 			changedFiles = scmChangedFiles
 		}
-		if hasRepoGlobalFileChanged, err := repoGlobalFileHasChanged(o, changedFiles); err != nil {
-			return nil, err
-		} else if hasRepoGlobalFileChanged {
-			allPkgs := make(util.Set)
-			for pkg := range packageInfos {
-				allPkgs.Add(pkg)
-			}
-			return allPkgs, nil
-		}
-		filteredChangedFiles, err := filterIgnoredFiles(o, changedFiles)
-		if err != nil {
-			return nil, err
+		return ChangedPackages(o, changedFiles, packageInfos)
+	}
+}
+
+// ChangedPackages returns the packages containing the given changed files, which are
+// repo-relative system paths. Files outside of any package are attributed to the root
+// package. If a global dependency changed, every package is considered changed.
+func ChangedPackages(opts *Opts, changedFiles []string, packageInfos map[interface{}]*fs.PackageJSON) (util.Set, error) {
+	if hasRepoGlobalFileChanged, err := repoGlobalFileHasChanged(opts, changedFiles); err != nil {
+		return nil, err
+	} else if hasRepoGlobalFileChanged {
+		allPkgs := make(util.Set)
+		for pkg := range packageInfos {
+			allPkgs.Add(pkg)
 		}
-		changedPkgs := getChangedPackages(filteredChangedFiles, packageInfos)
-		return changedPkgs, nil
+		return allPkgs, nil
+	}
+	filteredChangedFiles, err := filterIgnoredFiles(opts, changedFiles)
+	if err != nil {
+		return nil, err
 	}
+	changedPkgs := getChangedPackages(filteredChangedFiles, packageInfos)
+	return changedPkgs, nil
 }
 
 func repoGlobalFileHasChanged(opts *Opts, changedFiles []string) (bool, error) {
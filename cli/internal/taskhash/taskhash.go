@@ -5,13 +5,14 @@ package taskhash
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 
 	"github.com/pyr-sh/dag"
 	gitignore "github.com/sabhiram/go-gitignore"
-	"github.com/vercel/turborepo/cli/internal/doublestar"
 	"github.com/vercel/turborepo/cli/internal/env"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"github.com/vercel/turborepo/cli/internal/inference"
@@ -31,19 +32,28 @@ type Tracker struct {
 	globalHash          string
 	pipeline            fs.Pipeline
 	packageInfos        map[interface{}]*fs.PackageJSON
+	frameworks          []inference.Framework
 	mu                  sync.RWMutex
 	packageInputsHashes packageFileHashes
 	packageTaskHashes   map[string]string // taskID -> hash
+	// packageTaskOutputsHashes are only populated when hashing dependencies by their outputs.
+	// When present, they take the place of the task hash when calculating dependent task hashes.
+	packageTaskOutputsHashes map[string]string   // taskID -> hash of outputs
+	packageTaskEnvVars       map[string][]string // taskID -> names of env vars included in the hash
 }
 
 // NewTracker creates a tracker for package-inputs combinations and package-task combinations.
-func NewTracker(rootNode string, globalHash string, pipeline fs.Pipeline, packageInfos map[interface{}]*fs.PackageJSON) *Tracker {
+// frameworks are the frameworks to infer packages against, in order of priority.
+func NewTracker(rootNode string, globalHash string, pipeline fs.Pipeline, packageInfos map[interface{}]*fs.PackageJSON, frameworks []inference.Framework) *Tracker {
 	return &Tracker{
-		rootNode:          rootNode,
-		globalHash:        globalHash,
-		pipeline:          pipeline,
-		packageInfos:      packageInfos,
-		packageTaskHashes: make(map[string]string),
+		rootNode:                 rootNode,
+		globalHash:               globalHash,
+		pipeline:                 pipeline,
+		packageInfos:             packageInfos,
+		frameworks:               frameworks,
+		packageTaskHashes:        make(map[string]string),
+		packageTaskOutputsHashes: make(map[string]string),
+		packageTaskEnvVars:       make(map[string][]string),
 	}
 }
 
@@ -51,12 +61,15 @@ func NewTracker(rootNode string, globalHash string, pipeline fs.Pipeline, packag
 type packageFileSpec struct {
 	pkg    string
 	inputs []string
+	// dotEnv files are always hashed, even if they are gitignored or not matched by inputs
+	dotEnv []string
 }
 
 func specFromPackageTask(pt *nodes.PackageTask) packageFileSpec {
 	return packageFileSpec{
 		pkg:    pt.PackageName,
 		inputs: pt.TaskDefinition.Inputs,
+		dotEnv: pt.TaskDefinition.DotEnv,
 	}
 }
 
@@ -65,7 +78,8 @@ type packageFileHashKey string
 
 func (pfs packageFileSpec) ToKey() packageFileHashKey {
 	sort.Strings(pfs.inputs)
-	return packageFileHashKey(fmt.Sprintf("%v#%v", pfs.pkg, strings.Join(pfs.inputs, "!")))
+	// dotEnv order is significant, so it is not sorted
+	return packageFileHashKey(fmt.Sprintf("%v#%v#%v", pfs.pkg, strings.Join(pfs.inputs, "!"), strings.Join(pfs.dotEnv, "!")))
 }
 
 func safeCompileIgnoreFile(filepath string) (*gitignore.GitIgnore, error) {
@@ -88,10 +102,26 @@ func (pfs *packageFileSpec) hash(pkg *fs.PackageJSON, repoRoot fs.AbsolutePath)
 		}
 		hashObject = manualHashObject
 	}
+	for _, dotEnvFile := range pfs.dotEnv {
+		filename := repoRoot.Join(pkg.Dir, filepath.FromSlash(dotEnvFile))
+		if !filename.FileExists() {
+			continue
+		}
+		hash, err := fs.GitLikeHashFile(filename.ToString())
+		if err != nil {
+			return "", fmt.Errorf("could not hash dotEnv file %v: %w", filename, err)
+		}
+		hashObject[turbopath.AnchoredUnixPath(path.Clean(dotEnvFile))] = hash
+	}
 	hashOfFiles, otherErr := fs.HashObject(hashObject)
 	if otherErr != nil {
 		return "", otherErr
 	}
+	// Later .env files override earlier ones, so their order matters too. It is only
+	// hashed when there are .env files, so that the hashes of other tasks are unchanged.
+	if len(pfs.dotEnv) > 0 {
+		return fs.HashObject([]string{hashOfFiles, strings.Join(pfs.dotEnv, "!")})
+	}
 	return hashOfFiles, nil
 }
 
@@ -109,42 +139,43 @@ func manuallyHashPackage(pkg *fs.PackageJSON, inputs []string, rootPath fs.Absol
 		return nil, err
 	}
 
-	includePattern := ""
-	if len(inputs) > 0 {
-		includePattern = "{" + strings.Join(inputs, ",") + "}"
-	}
-
 	pathPrefix := rootPath.Join(pkg.Dir).ToString()
 	convertedPathPrefix := turbopath.AbsoluteSystemPathFromUpstream(pathPrefix)
-	fs.Walk(pathPrefix, func(name string, isDir bool) error {
-		convertedName := turbopath.AbsoluteSystemPathFromUpstream(name)
-		rootMatch := ignore.MatchesPath(convertedName.ToString())
-		otherMatch := ignorePkg.MatchesPath(convertedName.ToString())
-		if !rootMatch && !otherMatch {
-			if !isDir {
-				if includePattern != "" {
-					val, err := doublestar.PathMatch(includePattern, convertedName.ToString())
-					if err != nil {
-						return err
-					}
-					if !val {
-						return nil
-					}
-				}
-				hash, err := fs.GitLikeHashFile(convertedName.ToString())
-				if err != nil {
-					return fmt.Errorf("could not hash file %v. \n%w", convertedName.ToString(), err)
-				}
-
-				relativePath, err := convertedName.RelativeTo(convertedPathPrefix)
-				if err != nil {
-					return fmt.Errorf("File path cannot be made relative: %w", err)
-				}
-				hashObject[relativePath.ToUnixPath()] = hash
+	patterns := fs.ParseInputPatterns(pkg.Dir, inputs)
+	for _, searchPath := range patterns.SearchPaths() {
+		searchRoot := filepath.Join(pathPrefix, filepath.FromSlash(searchPath))
+		if !fs.PathExists(searchRoot) {
+			continue
+		}
+		err := fs.Walk(searchRoot, func(name string, isDir bool) error {
+			convertedName := turbopath.AbsoluteSystemPathFromUpstream(name)
+			rootMatch := ignore.MatchesPath(convertedName.ToString())
+			otherMatch := ignorePkg.MatchesPath(convertedName.ToString())
+			if rootMatch || otherMatch || isDir {
+				return nil
+			}
+			relativePath, err := convertedName.RelativeTo(convertedPathPrefix)
+			if err != nil {
+				return fmt.Errorf("File path cannot be made relative: %w", err)
+			}
+			matches, err := patterns.Matches(relativePath.ToUnixPath().ToString())
+			if err != nil {
+				return err
 			}
+			if !matches {
+				return nil
+			}
+			hash, err := fs.GitLikeHashFile(convertedName.ToString())
+			if err != nil {
+				return fmt.Errorf("could not hash file %v. \n%w", convertedName.ToString(), err)
+			}
+			hashObject[relativePath.ToUnixPath()] = hash
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		return nil
-	})
+	}
 	return hashObject, nil
 }
 
@@ -175,6 +206,7 @@ func (th *Tracker) CalculateFileHashes(allTasks []dag.Vertex, workerCount int, r
 		hashTasks.Add(&packageFileSpec{
 			pkg:    pkgName,
 			inputs: taskDefinition.Inputs,
+			dotEnv: taskDefinition.DotEnv,
 		})
 	}
 
@@ -239,6 +271,10 @@ func (th *Tracker) calculateDependencyHashes(dependencySet dag.Set) ([]string, e
 		if strings.HasPrefix(dependencyTask, rootPrefix) {
 			continue
 		}
+		if outputsHash, ok := th.packageTaskOutputsHashes[dependencyTask]; ok {
+			dependencyHashSet.Add(outputsHash)
+			continue
+		}
 		dependencyHash, ok := th.packageTaskHashes[dependencyTask]
 		if !ok {
 			return nil, fmt.Errorf("missing hash for dependent task: %v", dependencyTask)
@@ -250,6 +286,15 @@ func (th *Tracker) calculateDependencyHashes(dependencySet dag.Set) ([]string, e
 	return dependenciesHashList, nil
 }
 
+// InferFramework returns the framework matching the package of the given task, if any.
+// Tasks may opt out of inference via their task definition.
+func (th *Tracker) InferFramework(pt *nodes.PackageTask) *inference.Framework {
+	if !pt.TaskDefinition.FrameworkInference {
+		return nil
+	}
+	return inference.InferFrameworkFrom(th.frameworks, pt.Pkg)
+}
+
 // CalculateTaskHash calculates the hash for package-task combination. It is threadsafe, provided
 // that it has previously been called on its task-graph dependencies. File hashes must be calculated
 // first.
@@ -261,12 +306,15 @@ func (th *Tracker) CalculateTaskHash(pt *nodes.PackageTask, dependencySet dag.Se
 	}
 
 	var envPrefixes []string
-	framework := inference.InferFramework(pt.Pkg)
+	framework := th.InferFramework(pt)
 	if framework != nil && framework.EnvPrefix != "" {
 		envPrefixes = append(envPrefixes, framework.EnvPrefix)
 	}
 
 	hashableEnvPairs := env.GetHashableEnvPairs(pt.TaskDefinition.EnvVarDependencies, envPrefixes)
+	if pt.TaskDefinition.IsVariant() {
+		hashableEnvPairs = withVariantEnv(hashableEnvPairs, pt.TaskDefinition.Variant)
+	}
 	outputs := pt.HashableOutputs()
 	taskDependencyHashes, err := th.calculateDependencyHashes(dependencySet)
 	if err != nil {
@@ -285,8 +333,44 @@ func (th *Tracker) CalculateTaskHash(pt *nodes.PackageTask, dependencySet dag.Se
 	if err != nil {
 		return "", fmt.Errorf("failed to hash task %v: %v", pt.TaskID, hash)
 	}
+	envVarNames := make([]string, len(hashableEnvPairs))
+	for i, pair := range hashableEnvPairs {
+		envVarNames[i] = strings.SplitN(pair, "=", 2)[0]
+	}
 	th.mu.Lock()
 	th.packageTaskHashes[pt.TaskID] = hash
+	th.packageTaskEnvVars[pt.TaskID] = envVarNames
 	th.mu.Unlock()
 	return hash, nil
 }
+
+// withVariantEnv replaces the values of the environment variables that a matrix variant
+// sets, since the variant's values are the ones that its command sees
+func withVariantEnv(envPairs []string, variant fs.TaskVariant) []string {
+	pairs := make([]string, 0, len(envPairs)+len(variant.Env))
+	for _, pair := range envPairs {
+		if _, ok := variant.Env[strings.SplitN(pair, "=", 2)[0]]; !ok {
+			pairs = append(pairs, pair)
+		}
+	}
+	pairs = append(pairs, variant.EnvPairs()...)
+	sort.Strings(pairs)
+	return pairs
+}
+
+// EnvVarNames returns the names of the environment variables that were included in the
+// hash of the given task. Values are omitted, since they may be secrets.
+func (th *Tracker) EnvVarNames(taskID string) []string {
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+	return th.packageTaskEnvVars[taskID]
+}
+
+// SetTaskOutputsHash records the hash of the outputs produced or restored by the given task.
+// Tasks that depend on it will use this hash instead of its task hash, so that changes to
+// its inputs that don't affect its outputs don't invalidate its dependents.
+func (th *Tracker) SetTaskOutputsHash(taskID string, hash string) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	th.packageTaskOutputsHashes[taskID] = hash
+}
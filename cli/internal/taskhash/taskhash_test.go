@@ -3,9 +3,11 @@ package taskhash
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/pyr-sh/dag"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"github.com/vercel/turborepo/cli/internal/turbopath"
 )
@@ -135,4 +137,98 @@ func Test_manuallyHashPackage(t *testing.T) {
 	if count != len(justFileHashes) {
 		t.Errorf("found extra hashes in %v", hashes)
 	}
+
+	withRootHashes, err := manuallyHashPackage(pkg, []string{"$TURBO_DEFAULT$", "$ROOT/top-level-file", "!some-dir/**"}, fs.AbsolutePath(repoRoot.ToString()))
+	if err != nil {
+		t.Fatalf("failed to calculate manual hashes: %v", err)
+	}
+	wantWithRoot := map[turbopath.AnchoredUnixPath]string{
+		"some-file":         files["libA/some-file"].hash,
+		".gitignore":        files["libA/.gitignore"].hash,
+		"../top-level-file": "561961a68c548da18a4d33915c9a52c3d613b206",
+	}
+	if !reflect.DeepEqual(withRootHashes, wantWithRoot) {
+		t.Errorf("manuallyHashPackage() got %v, want %v", withRootHashes, wantWithRoot)
+	}
+}
+
+func Test_calculateDependencyHashes(t *testing.T) {
+	tracker := NewTracker("___ROOT___", "global-hash", fs.Pipeline{}, map[interface{}]*fs.PackageJSON{}, nil)
+	tracker.packageTaskHashes["libA#build"] = "libA-task-hash"
+	tracker.packageTaskHashes["libB#build"] = "libB-task-hash"
+	deps := make(dag.Set)
+	deps.Add("libA#build")
+	deps.Add("libB#build")
+	deps.Add("___ROOT___")
+
+	got, err := tracker.calculateDependencyHashes(deps)
+	if err != nil {
+		t.Fatalf("failed to calculate dependency hashes: %v", err)
+	}
+	want := []string{"libA-task-hash", "libB-task-hash"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("calculateDependencyHashes() got %v, want %v", got, want)
+	}
+
+	// Once a dependency's outputs are known, they stand in for its task hash
+	tracker.SetTaskOutputsHash("libA#build", "libA-outputs-hash")
+	got, err = tracker.calculateDependencyHashes(deps)
+	if err != nil {
+		t.Fatalf("failed to calculate dependency hashes: %v", err)
+	}
+	want = []string{"libA-outputs-hash", "libB-task-hash"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("calculateDependencyHashes() got %v, want %v", got, want)
+	}
+}
+
+func Test_withVariantEnv(t *testing.T) {
+	variant := fs.TaskVariant{Name: "edge", Env: map[string]string{"TARGET": "edge", "RUNTIME": "workers"}}
+	got := withVariantEnv([]string{"NODE_ENV=production", "TARGET=node"}, variant)
+	want := []string{"NODE_ENV=production", "RUNTIME=workers", "TARGET=edge"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withVariantEnv() got %v, want %v", got, want)
+	}
+}
+
+func Test_packageFileSpecDotEnv(t *testing.T) {
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	pkg := &fs.PackageJSON{Dir: "libA"}
+	for name, contents := range map[string]string{
+		"libA/index.js":   "module.exports = 1",
+		"libA/.env":       "A=1",
+		"libA/.env.local": "A=2",
+	} {
+		path := repoRoot.Join(filepath.FromSlash(name))
+		if err := path.EnsureDir(); err != nil {
+			t.Fatalf("failed to create directory for %v: %v", name, err)
+		}
+		if err := path.WriteFile([]byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write %v: %v", name, err)
+		}
+	}
+	hash := func(dotEnv []string) string {
+		spec := packageFileSpec{pkg: "libA", dotEnv: dotEnv}
+		h, err := spec.hash(pkg, repoRoot)
+		if err != nil {
+			t.Fatalf("failed to hash %v: %v", dotEnv, err)
+		}
+		return h
+	}
+
+	// Without dotEnv, the hash is that of the package's files alone
+	hashObject, err := manuallyHashPackage(pkg, nil, repoRoot)
+	if err != nil {
+		t.Fatalf("failed to hash package: %v", err)
+	}
+	expected, err := fs.HashObject(hashObject)
+	if err != nil {
+		t.Fatalf("failed to hash files: %v", err)
+	}
+	if got := hash(nil); got != expected {
+		t.Errorf("hash without dotEnv got %v, want %v", got, expected)
+	}
+	if hash([]string{".env", ".env.local"}) == hash([]string{".env.local", ".env"}) {
+		t.Error("expected the order of dotEnv files to change the hash")
+	}
 }
@@ -28,7 +28,7 @@ func IsNMLinker(cwd string) (bool, error) {
 		return false, fmt.Errorf(".yarnrc.yml: %w", err)
 	}
 
-	if yaml.Unmarshal(bytes, yarnRC) != nil {
+	if err := yaml.Unmarshal(bytes, yarnRC); err != nil {
 		return false, fmt.Errorf(".yarnrc.yml: %w", err)
 	}
 
@@ -51,6 +51,45 @@ export interface Schema {
    * @default {}
    */
   remoteCache?: RemoteCache;
+
+  /**
+   * Whether to infer the framework used by each package, and include environment variables
+   * with that framework's public prefix (e.g. NEXT_PUBLIC_) in the package's task hashes.
+   *
+   * @default true
+   */
+  frameworkInference?: boolean;
+
+  /**
+   * Additional frameworks to infer. These are checked before the builtin frameworks, in order.
+   *
+   * @default []
+   */
+  frameworks?: Framework[];
+}
+
+export interface Framework {
+  /**
+   * A name for the framework, shown in --dry output.
+   */
+  slug: string;
+
+  /**
+   * Environment variables beginning with this prefix are included in task hashes.
+   */
+  envPrefix: string;
+
+  /**
+   * Package names to look for in a package's dependencies.
+   */
+  dependencies: string[];
+
+  /**
+   * Use "all" to require every dependency to be present, or "some" to require at least one.
+   *
+   * @default all
+   */
+  match?: "all" | "some";
 }
 
 export interface Pipeline {
@@ -103,6 +142,10 @@ export interface Pipeline {
    * will not cause a cache miss.
    *
    * If omitted or empty, all files in the package are considered as inputs.
+   *
+   * Globs are relative to the package. Prefix a glob with $ROOT/ to make it relative to
+   * the root of the repository, or with ! to exclude the files it matches. Include
+   * $TURBO_DEFAULT$ to start from all files in the package.
    * @default []
    */
   inputs?: string[];
@@ -116,6 +159,123 @@ export interface Pipeline {
    * @default full
    */
   outputMode?: string;
+
+  /**
+   * Whether to infer the framework of the package this task runs in. Set to false to keep
+   * framework env prefixes out of this task's hash.
+   *
+   * @default true
+   */
+  frameworkInference?: boolean;
+
+  /**
+   * A list of .env files, relative to the package, to load into this task's environment.
+   * Their contents are included in the task's hash. Later files take precedence over
+   * earlier ones, and variables already set in the environment take precedence over all
+   * files. Files that don't exist are skipped.
+   *
+   * @default []
+   */
+  dotEnv?: string[];
+
+  /**
+   * Whether this task is a long-running process, such as a dev server, that never exits.
+   * Persistent tasks are never cached, and other tasks may not depend on them.
+   *
+   * @default false
+   */
+  persistent?: boolean;
+
+  /**
+   * The share of the machine each instance of this task uses. Running tasks are limited
+   * so that their total fits within --concurrency and --memory-budget.
+   *
+   * @default { "cpu": 1 }
+   */
+  resources?: Resources;
+
+  /**
+   * The number of times to re-run this task if its command fails. Tasks which only
+   * succeed after being retried are reported as flaky.
+   *
+   * @default 0
+   */
+  retries?: number;
+
+  /**
+   * How long to wait before each retry, as a duration such as "10s".
+   *
+   * @default "0s"
+   */
+  retryDelay?: string;
+
+  /**
+   * The longest this task may run, as a duration such as "10m". The task is
+   * interrupted, then killed, if it runs longer, and reported as timed out.
+   *
+   * @default undefined
+   */
+  timeout?: string;
+
+  /**
+   * The variants of this task. Each variant runs the task's script as a separate
+   * task named "<task>@<name>", with its own hash, logs and cache entry.
+   *
+   * @default []
+   */
+  matrix?: TaskVariant[];
+
+  /**
+   * Arguments to pass to the task's script. They are replaced by `--args` for
+   * this task, and arguments after `--` are added after them.
+   *
+   * @default []
+   */
+  args?: string[];
+}
+
+export interface TaskVariant {
+  /**
+   * The name of the variant. May contain letters, digits, "-", "_" and ".".
+   */
+  name: string;
+
+  /**
+   * Environment variables to set for the variant's command. They are included
+   * in the variant's hash.
+   *
+   * @default {}
+   */
+  env?: Record<string, string>;
+
+  /**
+   * Arguments to pass to the task's script.
+   *
+   * @default []
+   */
+  args?: string[];
+
+  /**
+   * The outputs of the variant, in place of the task's outputs. The outputs
+   * of a task's variants may not overlap.
+   */
+  outputs?: string[];
+}
+
+export interface Resources {
+  /**
+   * The number of concurrency slots the task occupies.
+   *
+   * @default 1
+   */
+  cpu?: number;
+
+  /**
+   * The memory, in megabytes, the task is expected to use.
+   *
+   * @default 0
+   */
+  memoryMB?: number;
 }
 
 export interface RemoteCache {